@@ -0,0 +1,129 @@
+package api2go
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// redacted replaces the values of redacted fields and query parameters
+const redacted = "[REDACTED]"
+
+// AccessLogConfig configures the access log, which writes one logrus entry
+// per request to a generated route with the fields request_id, resource,
+// operation, id, method, path, query, status, duration_ms, size and, for
+// failed requests, error and error_code. Request and response bodies, and so
+// attribute values, are never logged. The error is logged with the message
+// the resource returned, remove it with Redact if it may contain values.
+type AccessLogConfig struct {
+	// Logger defaults to the logrus standard logger
+	Logger *logrus.Logger
+	// Level is the level of successful requests, InfoLevel if nil.
+	// Server errors are logged at least at ErrorLevel.
+	Level *logrus.Level
+	// ResourceLevels overrides Level per resource name
+	ResourceLevels map[string]logrus.Level
+	// RedactedFields are entry fields and query parameters whose values are
+	// replaced, a name also matches parameters like filter[name]
+	RedactedFields []string
+	// Redact is called with the fields of every entry before it is written
+	Redact func(fields logrus.Fields)
+}
+
+// SetAccessLog enables the access log. Each request gets a request id, which
+// is taken from the X-Request-ID header if present, returned in the same
+// header and used for error correlation ids.
+func (api *API) SetAccessLog(config AccessLogConfig) {
+	if config.Logger == nil {
+		config.Logger = logrus.StandardLogger()
+	}
+	api.accessLog = &config
+}
+
+func (config *AccessLogConfig) level(resourceName string, status int) logrus.Level {
+	level := logrus.InfoLevel
+	if config.Level != nil {
+		level = *config.Level
+	}
+	if resourceLevel, ok := config.ResourceLevels[resourceName]; ok {
+		level = resourceLevel
+	}
+	if status >= http.StatusInternalServerError && level > logrus.ErrorLevel {
+		level = logrus.ErrorLevel
+	}
+	return level
+}
+
+func (config *AccessLogConfig) redacts(name string) bool {
+	for _, field := range config.RedactedFields {
+		if name == field || strings.HasSuffix(name, "["+field+"]") {
+			return true
+		}
+	}
+	return false
+}
+
+func (config *AccessLogConfig) query(values url.Values) string {
+	if len(config.RedactedFields) == 0 {
+		return values.Encode()
+	}
+	cleaned := make(url.Values, len(values))
+	for name, value := range values {
+		if config.redacts(name) {
+			cleaned[name] = []string{redacted}
+		} else {
+			cleaned[name] = value
+		}
+	}
+	return cleaned.Encode()
+}
+
+// logAccess writes the access log entry of a finished request
+func (api *API) logAccess(res *resource, operation Operation, w *metricsWriter, r *http.Request, params map[string]string, start time.Time, err error) {
+	config := api.accessLog
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	fields := logrus.Fields{
+		"request_id":  RequestID(r.Context()),
+		"resource":    res.name,
+		"operation":   string(operation),
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"status":      status,
+		"duration_ms": float64(time.Since(start).Microseconds()) / 1000,
+		"size":        w.size,
+	}
+	if id, ok := params["id"]; ok {
+		fields["id"] = id
+	}
+	if len(r.URL.RawQuery) > 0 {
+		fields["query"] = config.query(r.URL.Query())
+	}
+	if err != nil {
+		httpError, _ := api.errorHandling.toHTTPError(err)
+		fields["error"] = httpError.msg
+		for _, e := range httpError.Errors {
+			if e.Code != "" {
+				fields["error_code"] = e.Code
+				break
+			}
+		}
+	}
+
+	for name := range fields {
+		if config.redacts(name) {
+			fields[name] = redacted
+		}
+	}
+	if config.Redact != nil {
+		config.Redact(fields)
+	}
+
+	config.Logger.WithFields(fields).Log(config.level(res.name, status), "request")
+}
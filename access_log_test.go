@@ -0,0 +1,104 @@
+package api2go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+	"github.com/sirupsen/logrus"
+)
+
+// accessLogEntries returns the entries logged for the requests sent by send
+func accessLogEntries(t *testing.T, config api2go.AccessLogConfig, send func(c testClient)) []map[string]interface{} {
+	t.Helper()
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	config.Logger = logrus.New()
+	config.Logger.SetOutput(&out)
+	config.Logger.SetFormatter(&logrus.JSONFormatter{})
+	config.Logger.SetLevel(logrus.DebugLevel)
+	api.SetAccessLog(config)
+
+	send(testClient{t, api.Handler()})
+
+	entries := make([]map[string]interface{}, 0)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("%v in %s", err, line)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestAccessLogLevels(t *testing.T) {
+	debug, warn := logrus.DebugLevel, logrus.WarnLevel
+	tests := []struct {
+		name   string
+		config api2go.AccessLogConfig
+		want   string
+	}{
+		{"default", api2go.AccessLogConfig{}, "info"},
+		{"explicit", api2go.AccessLogConfig{Level: &debug}, "debug"},
+		{"per resource", api2go.AccessLogConfig{Level: &debug, ResourceLevels: map[string]logrus.Level{"user": logrus.WarnLevel}}, "warning"},
+		{"trace is filtered", api2go.AccessLogConfig{Level: &warn, ResourceLevels: map[string]logrus.Level{"user": logrus.TraceLevel}}, ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			entries := accessLogEntries(t, test.config, func(c testClient) {
+				c.do("GET", "user/u1", nil).expect(http.StatusOK)
+			})
+			if test.want == "" {
+				if len(entries) != 0 {
+					t.Errorf("expected no entries, got %v", entries)
+				}
+				return
+			}
+			if len(entries) != 1 || entries[0]["level"] != test.want {
+				t.Errorf("expected one entry at level %s, got %v", test.want, entries)
+			}
+		})
+	}
+}
+
+func TestAccessLogFields(t *testing.T) {
+	entries := accessLogEntries(t, api2go.AccessLogConfig{RedactedFields: []string{"name"}}, func(c testClient) {
+		c.do("PATCH", "user/u1", resource("user", "u1", map[string]interface{}{"name": "secret"}, nil)).expect(http.StatusOK)
+		c.do("GET", "user?filter[name]=secret", nil).expect(http.StatusOK)
+		c.do("GET", "user/unknown", nil).expect(http.StatusNotFound)
+	})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %v", entries)
+	}
+
+	for _, entry := range entries {
+		encoded, _ := json.Marshal(entry)
+		if strings.Contains(string(encoded), "secret") {
+			t.Errorf("expected no attribute or redacted value to be logged, got %s", encoded)
+		}
+		if entry["request_id"] == "" || entry["resource"] != "user" || entry["msg"] != "request" {
+			t.Errorf("unexpected entry %v", entry)
+		}
+	}
+	if update := entries[0]; update["operation"] != "update" || update["id"] != "u1" || update["method"] != "PATCH" || update["status"] != float64(200) {
+		t.Errorf("unexpected update entry %v", update)
+	}
+	if list := entries[1]; !strings.Contains(list["query"].(string), "REDACTED") {
+		t.Errorf("expected the filter value to be redacted, got %v", list["query"])
+	}
+	if failed := entries[2]; failed["status"] != float64(404) || failed["error"] == nil {
+		t.Errorf("expected the error of the failed request, got %v", failed)
+	}
+}
@@ -6,32 +6,41 @@ import (
 	"github.com/artpar/api2go/v2/jsonapi"
 	jsoniter "github.com/json-iterator/go"
 	"io/ioutil"
-	"log"
 	"net/http"
 	"net/url"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
-
-	"github.com/artpar/api2go/v2/routing"
+	"time"
 )
 
 var jsonLib = jsoniter.ConfigCompatibleWithStandardLibrary
 
 const (
 	codeInvalidQueryFields  = "API2GO_INVALID_FIELD_QUERY_PARAM"
+	codeInvalidSortParam    = "API2GO_INVALID_SORT_PARAM"
+	codeInvalidFilterParam  = "API2GO_INVALID_FILTER_PARAM"
+	codeInvalidPageParam    = "API2GO_INVALID_PAGE_PARAM"
+	codeTypeConflict        = "API2GO_TYPE_CONFLICT"
+	codeClientIDForbidden   = "API2GO_CLIENT_ID_FORBIDDEN"
+	codeClientIDRequired    = "API2GO_CLIENT_ID_REQUIRED"
+	codeInvalidClientID     = "API2GO_INVALID_CLIENT_ID"
+	codeRateLimited         = "API2GO_RATE_LIMITED"
+	codeUnsupportedVersion  = "API2GO_UNSUPPORTED_VERSION"
 	defaultContentTypHeader = "application/vnd.api+json"
 )
 
 var (
 	queryPageRegex   = regexp.MustCompile(`^page\[(\w+)\]$`)
-	queryFieldsRegex = regexp.MustCompile(`^fields\[(\w+)\]$`)
+	queryFieldsRegex = regexp.MustCompile(`^fields\[([\w-]+)\]$`)
+	queryFilterRegex = regexp.MustCompile(`^filter\[([\w.-]+)\]$`)
 )
 
 type information struct {
 	prefix   string
 	resolver URLResolver
+	naming   jsonapi.NamingStrategy
 }
 
 func (i information) GetBaseURL() string {
@@ -42,6 +51,10 @@ func (i information) GetPrefix() string {
 	return i.prefix
 }
 
+func (i information) GetNamingStrategy() jsonapi.NamingStrategy {
+	return i.naming
+}
+
 type paginationQueryParams struct {
 	number, size, offset, limit string
 }
@@ -193,21 +206,100 @@ type notAllowedHandler struct {
 
 func (n notAllowedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	err := NewHTTPError(nil, "Method Not Allowed", http.StatusMethodNotAllowed)
-	w.WriteHeader(http.StatusMethodNotAllowed)
 
-	contentType := defaultContentTypHeader
-	if n.API != nil {
-		contentType = n.API.ContentType
+	if n.API == nil {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		handleError(err, w, r, defaultContentTypHeader)
+		return
 	}
-
-	handleError(err, w, r, contentType)
+	n.API.writeCORSHeaders(w, r, nil)
+	n.API.writeDeprecationHeaders(w, "")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	n.API.handleError(err, w, r)
 }
 
 type resource struct {
 	resourceType reflect.Type
+	prototype    jsonapi.MarshalIdentifier
 	source       interface{}
 	name         string
 	api          *API
+	// memberNames maps member names in documents to the names of the
+	// resource, documentNames the other way round
+	memberNames   map[string]string
+	documentNames map[string]string
+}
+
+// routeHandler is the signature of the handlers of generated routes
+type routeHandler func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error
+
+// handle registers a generated route. It takes care of the context pool,
+// middlewares, CORS headers, rate limits, panics, observers, tracing, the
+// access log and error documents, so handlers only deal with the request
+// itself.
+func (api *API) handle(res *resource, relation *jsonapi.Reference, operation Operation, method, path string, handler routeHandler) {
+	api.addRoute(routeEntry{res: res, relation: relation, operation: operation, method: method, path: path})
+	api.route(res, method, path, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		var err error
+		c := api.contextPool.Get().(APIContexter)
+		c.Reset()
+		defer api.contextPool.Put(c)
+		if api.accessLog != nil {
+			r = withRequestID(w, r)
+		}
+		if len(api.observers) > 0 || api.accessLog != nil {
+			mw := &metricsWriter{ResponseWriter: w}
+			start := time.Now()
+			defer func() {
+				api.observe(res, operation, mw, start)
+				if api.accessLog != nil {
+					api.logAccess(res, operation, mw, r, params, start, err)
+				}
+			}()
+			w = mw
+		}
+		r, span := api.startRequestSpan(r, res, operation, params)
+		defer span.End()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				span.SetError(fmt.Errorf("panic: %v", recovered))
+				api.recoverPanic(w, r, recovered)
+			}
+		}()
+		api.writeCORSHeaders(w, r, nil)
+		api.writeDeprecationHeaders(w, res.name)
+		api.middlewareChain(c, w, r)
+		err = api.checkRateLimit(c, w, r, res.name, operation)
+		if err == nil {
+			err = handler(c, w, r, params)
+		}
+		if err != nil {
+			err = res.documentErrors(err)
+			span.SetError(err)
+			api.handleError(err, w, r)
+		}
+	})
+}
+
+// handleOptions registers the OPTIONS route of path, which answers with the
+// allowed methods and CORS preflight headers
+func (api *API) handleOptions(res *resource, relation *jsonapi.Reference, path string, allowedMethods []string) {
+	api.addRoute(routeEntry{res: res, relation: relation, method: http.MethodOptions, path: path})
+	api.route(res, http.MethodOptions, path, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		c := api.contextPool.Get().(APIContexter)
+		c.Reset()
+		defer api.contextPool.Put(c)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				api.recoverPanic(w, r, recovered)
+			}
+		}()
+		api.writeCORSHeaders(w, r, allowedMethods)
+		api.writeDeprecationHeaders(w, res.name)
+		api.middlewareChain(c, w, r)
+		w.Header().Set("Allow", strings.Join(allowedMethods, ","))
+		w.WriteHeader(http.StatusNoContent)
+	})
 }
 
 // middlewareChain executes the middleeware chain setup
@@ -222,6 +314,19 @@ func (api *API) allocateDefaultContext() APIContexter {
 	return &APIContext{}
 }
 
+// resourceName returns the name of the resource of prototype
+func resourceName(prototype jsonapi.MarshalIdentifier) string {
+	// check if EntityNamer interface is implemented and use that as name
+	if entityName, ok := prototype.(jsonapi.EntityNamer); ok {
+		return entityName.GetName()
+	}
+	var name string
+	if resourceType := reflect.TypeOf(prototype); resourceType.Kind() == reflect.Ptr {
+		name = resourceType.Elem().Name()
+	}
+	return jsonapi.Jsonify(jsonapi.Pluralize(name))
+}
+
 func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interface{}) *resource {
 	resourceType := reflect.TypeOf(prototype)
 	if resourceType.Kind() != reflect.Struct && resourceType.Kind() != reflect.Ptr && resourceType.Kind() != reflect.Map {
@@ -229,7 +334,6 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	}
 
 	var ptrPrototype interface{}
-	var name string
 
 	if resourceType.Kind() == reflect.Struct {
 		ptrPrototype = reflect.New(resourceType).Interface()
@@ -237,29 +341,23 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		ptrPrototype = reflect.MakeMap(resourceType).Interface()
 	} else {
 		ptrPrototype = reflect.ValueOf(prototype).Interface()
-		name = resourceType.Elem().Name()
-	}
-
-	// check if EntityNamer interface is implemented and use that as name
-	entityName, ok := prototype.(jsonapi.EntityNamer)
-	if ok {
-		name = entityName.GetName()
-	} else {
-		name = jsonapi.Jsonify(jsonapi.Pluralize(name))
 	}
+	name := resourceName(prototype)
 
 	res := resource{
 		resourceType: resourceType,
+		prototype:    prototype,
 		name:         name,
 		source:       source,
 		api:          api,
 	}
+	res.setMemberNames()
 
 	requestInfo := func(r *http.Request, api *API) *information {
 		var info *information
 		if resolver, ok := api.info.resolver.(RequestAwareURLResolver); ok {
 			resolver.SetRequest(*r)
-			info = &information{prefix: api.info.prefix, resolver: resolver}
+			info = &information{prefix: api.info.prefix, resolver: resolver, naming: api.info.naming}
 		} else {
 			info = &api.info
 		}
@@ -267,53 +365,18 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 		return info
 	}
 
-	prefix := strings.Trim(api.info.prefix, "/")
-	baseURL := "/" + name
-	if prefix != "" {
-		baseURL = "/" + prefix + baseURL
-	}
+	baseURL := api.resourceBaseURL(name)
 
-	api.router.Handle("OPTIONS", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-		c := api.contextPool.Get().(APIContexter)
-		c.Reset()
-		api.middlewareChain(c, w, r)
-		w.Header().Set("Allow", strings.Join(getAllowedMethods(source, true), ","))
-		w.WriteHeader(http.StatusNoContent)
-		api.contextPool.Put(c)
-	})
+	api.handleOptions(&res, nil, baseURL, getAllowedMethods(source, true))
 
-	api.router.Handle("GET", baseURL, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-		info := requestInfo(r, api)
-		c := api.contextPool.Get().(APIContexter)
-		c.Reset()
-		api.middlewareChain(c, w, r)
-
-		err := res.handleIndex(c, w, r, *info)
-		api.contextPool.Put(c)
-		if err != nil {
-			handleError(err, w, r, api.ContentType)
-		}
+	api.handle(&res, nil, OperationFindAll, "GET", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string) error {
+		return res.handleIndex(c, w, r, *requestInfo(r, api))
 	})
 
 	if _, ok := source.(ResourceGetter); ok {
-		api.router.Handle("OPTIONS", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-			api.middlewareChain(c, w, r)
-			w.Header().Set("Allow", strings.Join(getAllowedMethods(source, false), ","))
-			w.WriteHeader(http.StatusNoContent)
-			api.contextPool.Put(c)
-		})
-		api.router.Handle("GET", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-			api.middlewareChain(c, w, r)
-			err := res.handleRead(c, w, r, params, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+		api.handleOptions(&res, nil, baseURL+"/:id", getAllowedMethods(source, false))
+		api.handle(&res, nil, OperationFindOne, "GET", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			return res.handleRead(c, w, r, params, *requestInfo(r, api))
 		})
 	}
 
@@ -322,120 +385,61 @@ func (api *API) addResource(prototype jsonapi.MarshalIdentifier, source interfac
 	if ok {
 		relations := casted.GetReferences()
 		for _, relation := range relations {
-			api.router.Handle("GET", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-					info := requestInfo(r, api)
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
-					api.middlewareChain(c, w, r)
-					err := res.handleReadRelation(c, w, r, params, *info, relation)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
-
-			api.router.Handle("GET", baseURL+"/:id/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-					info := requestInfo(r, api)
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
-					api.middlewareChain(c, w, r)
-					err := res.handleLinked(c, api, w, r, params, relation, *info)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
-
-			api.router.Handle("PATCH", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-					c := api.contextPool.Get().(APIContexter)
-					c.Reset()
-					api.middlewareChain(c, w, r)
-					err := res.handleReplaceRelation(c, w, r, params, relation)
-					api.contextPool.Put(c)
-					if err != nil {
-						handleError(err, w, r, api.ContentType)
-					}
-				}
-			}(relation))
+			relation := relation
+			relationshipMethods := []string{http.MethodOptions, http.MethodGet, http.MethodPatch}
+			relationshipURL := baseURL + "/:id/relationships/" + res.documentName(relation.Name)
+			relatedURL := baseURL + "/:id/" + res.documentName(relation.Name)
 
-			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && relation.Name == jsonapi.Pluralize(relation.Name) {
+			api.handle(&res, &relation, OperationReadRelationship, "GET", relationshipURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+				return res.handleReadRelation(c, w, r, params, *requestInfo(r, api), relation)
+			})
+
+			api.handleOptions(&res, &relation, relatedURL, []string{http.MethodOptions, http.MethodGet})
+			api.handle(&res, &relation, OperationFindRelated, "GET", relatedURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+				return res.handleLinked(c, api, w, r, params, relation, *requestInfo(r, api))
+			})
+
+			api.handle(&res, &relation, OperationReplaceRelationship, "PATCH", relationshipURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+				return res.handleReplaceRelation(c, w, r, params, relation)
+			})
+
+			if _, ok := ptrPrototype.(jsonapi.EditToManyRelations); ok && isToMany(relation) {
 				// generate additional routes to manipulate to-many relationships
-				api.router.Handle("POST", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-						api.middlewareChain(c, w, r)
-						err := res.handleAddToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							handleError(err, w, r, api.ContentType)
-						}
-					}
-				}(relation))
-
-				api.router.Handle("DELETE", baseURL+"/:id/relationships/"+relation.Name, func(relation jsonapi.Reference) routing.HandlerFunc {
-					return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-						c := api.contextPool.Get().(APIContexter)
-						c.Reset()
-						api.middlewareChain(c, w, r)
-						err := res.handleDeleteToManyRelation(c, w, r, params, relation)
-						api.contextPool.Put(c)
-						if err != nil {
-							handleError(err, w, r, api.ContentType)
-						}
-					}
-				}(relation))
+				relationshipMethods = append(relationshipMethods, http.MethodPost, http.MethodDelete)
+
+				api.handle(&res, &relation, OperationAddToMany, "POST", relationshipURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+					return res.handleAddToManyRelation(c, w, r, params, relation)
+				})
+
+				api.handle(&res, &relation, OperationDeleteFromMany, "DELETE", relationshipURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+					return res.handleDeleteToManyRelation(c, w, r, params, relation)
+				})
 			}
+
+			api.handleOptions(&res, &relation, relationshipURL, relationshipMethods)
 		}
 	}
 
 	if _, ok := source.(ResourceCreator); ok {
-		api.router.Handle("POST", baseURL, func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		api.handle(&res, nil, OperationCreate, "POST", baseURL, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string) error {
 			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-			api.middlewareChain(c, w, r)
-			err := res.handleCreate(c, w, r, info.prefix, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+			return res.handleCreate(c, w, r, info.prefix, *info)
 		})
 	}
 
 	if _, ok := source.(ResourceDeleter); ok {
-		api.router.Handle("DELETE", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-			api.middlewareChain(c, w, r)
-			err := res.handleDelete(c, w, r, params)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+		api.handle(&res, nil, OperationDelete, "DELETE", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			return res.handleDelete(c, w, r, params)
 		})
 	}
 
 	if _, ok := source.(ResourceUpdater); ok {
-		api.router.Handle("PATCH", baseURL+"/:id", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
-			info := requestInfo(r, api)
-			c := api.contextPool.Get().(APIContexter)
-			c.Reset()
-			api.middlewareChain(c, w, r)
-			err := res.handleUpdate(c, w, r, params, *info)
-			api.contextPool.Put(c)
-			if err != nil {
-				handleError(err, w, r, api.ContentType)
-			}
+		api.handle(&res, nil, OperationUpdate, "PATCH", baseURL+"/:id", func(c APIContexter, w http.ResponseWriter, r *http.Request, params map[string]string) error {
+			return res.handleUpdate(c, w, r, params, *requestInfo(r, api))
 		})
 	}
 
-	api.resources = append(api.resources, res)
+	api.putResource(res)
 
 	return &res
 }
@@ -480,6 +484,16 @@ func buildRequest(c APIContexter, r *http.Request) Request {
 	return req
 }
 
+// tracedRequest builds the Request for a call to the resource, which is
+// traced by the returned span
+func tracedRequest(c APIContexter, r *http.Request, name string) (Request, Span) {
+	r, span := startSpan(r, name)
+	if state, ok := r.Context().Value(traceKey{}).(*traceState); ok {
+		c.Set(traceContextKey, state)
+	}
+	return buildRequest(c, r), span
+}
+
 func (res *resource) marshalResponse(resp interface{}, w http.ResponseWriter, status int, r *http.Request) error {
 	filtered, err := filterSparseFields(resp, r)
 	if err != nil {
@@ -499,7 +513,9 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 		//fmt.Printf("handle index: %v\n : %v\n", reflect.TypeOf(res.source))
 		pagination := newPaginationQueryParams(r)
 
-		count, response, err := source.PaginatedFindAll(buildRequest(c, r))
+		request, span := res.listRequest(c, r, "PaginatedFindAll")
+		count, response, err := source.PaginatedFindAll(request)
+		endSpan(span, err)
 		if err != nil {
 			return err
 		}
@@ -518,7 +534,9 @@ func (res *resource) handleIndex(c APIContexter, w http.ResponseWriter, r *http.
 		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
 	}
 
-	response, err := source.FindAll(buildRequest(c, r))
+	request, span := res.listRequest(c, r, "FindAll")
+	response, err := source.FindAll(request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -534,7 +552,9 @@ func (res *resource) handleRead(c APIContexter, w http.ResponseWriter, r *http.R
 	}
 	id := params["id"]
 
-	response, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	response, err := source.FindOne(id, request)
+	endSpan(span, err)
 
 	if err != nil {
 		return err
@@ -550,8 +570,11 @@ func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r
 		return fmt.Errorf("Resource %s does not implement the ResourceGetter interface", res.name)
 	}
 	id := params["id"]
+	r = traceRelation(r, relation.Name)
 
-	obj, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	obj, err := source.FindOne(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -561,65 +584,184 @@ func (res *resource) handleReadRelation(c APIContexter, w http.ResponseWriter, r
 		return err
 	}
 
-	rel, ok := document.Data.DataObject.Relationships[relation.Name]
+	rel, ok := document.Data.DataObject.Relationships[info.naming.MemberName(obj.Result(), relation.Name)]
 	if !ok {
 		return NewHTTPError(nil, fmt.Sprintf("There is no relation with the name %s", relation.Name), http.StatusNotFound)
 	}
 
+	// ask the related resource if it can list the relationship itself
+	if related, ok := res.api.findResource(relation.Type); ok {
+		if finder, ok := related.source.(PaginatedRelatedFinder); ok && newPaginationQueryParams(r).isValid() {
+			request, span := related.listRequest(c, r, "PaginatedFindRelated")
+			count, response, err := finder.PaginatedFindRelated(res.name, id, relation.Name, request)
+			endSpan(span, err)
+			if err != nil {
+				return err
+			}
+			links, err := newPaginationQueryParams(r).getLinks(r, count, info)
+			if err != nil {
+				return err
+			}
+			rel.Links = links
+			if err := setRelationshipData(&rel, response.Result(), info.naming.Name(relation.Type)); err != nil {
+				return err
+			}
+		} else if finder, ok := related.source.(RelatedFinder); ok {
+			request, span := related.listRequest(c, r, "FindRelated")
+			response, err := finder.FindRelated(res.name, id, relation.Name, request)
+			endSpan(span, err)
+			if err != nil {
+				return err
+			}
+			if err := setRelationshipData(&rel, response.Result(), info.naming.Name(relation.Type)); err != nil {
+				return err
+			}
+		}
+	}
+
 	meta := obj.Metadata()
 	if len(meta) > 0 {
 		rel.Meta = meta
 	}
 
-	return res.marshalResponse(rel, w, http.StatusOK, r)
+	_, span = startSpan(r, "Marshal")
+	err = res.marshalResponse(rel, w, http.StatusOK, r)
+	endSpan(span, err)
+	return err
 }
 
-// try to find the referenced resource and call the findAll Method with referencing resource id as param
+// setRelationshipData replaces the linkage of rel with the identifiers of
+// result, which is a single object or a slice as returned by a RelatedFinder.
+// The form of the existing linkage decides between to-one and to-many.
+func setRelationshipData(rel *jsonapi.Relationship, result interface{}, relationType string) error {
+	toMany := rel.Data != nil && rel.Data.DataArray != nil
+	container := &jsonapi.RelationshipDataContainer{}
+	if toMany {
+		container.DataArray = []jsonapi.RelationshipData{}
+	}
+
+	if result != nil {
+		value := reflect.ValueOf(result)
+		elements := []reflect.Value{value}
+		if value.Kind() == reflect.Slice {
+			elements = make([]reflect.Value, 0, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				elements = append(elements, value.Index(i))
+			}
+		}
+		for _, element := range elements {
+			identifier, ok := element.Interface().(jsonapi.MarshalIdentifier)
+			if !ok {
+				return fmt.Errorf("related %s must implement MarshalIdentifier", relationType)
+			}
+			data := jsonapi.RelationshipData{Type: relationType, ID: identifier.GetID()}
+			if toMany {
+				container.DataArray = append(container.DataArray, data)
+			} else {
+				container.DataObject = &data
+				break
+			}
+		}
+	}
+
+	rel.Data = container
+	return nil
+}
+
+// findResource returns the registered resource with the given name
+func (api *API) findResource(name string) (*resource, bool) {
+	api.resourceMutex.RLock()
+	defer api.resourceMutex.RUnlock()
+	for i := range api.resources {
+		if api.resources[i].name == name {
+			return &api.resources[i], true
+		}
+	}
+	return nil, false
+}
+
+// try to find the referenced resource and let it list the related resources.
+// Resources which do not implement RelatedFinder get their FindAll method
+// called with the referencing resource id as param
 func (res *resource) handleLinked(c APIContexter, api *API, w http.ResponseWriter, r *http.Request, params map[string]string, linked jsonapi.Reference, info information) error {
 	id := params["id"]
-	for _, resource := range api.resources {
-		if resource.name == linked.Type {
-			request := buildRequest(c, r)
-			request.QueryParams[res.name+"_id"] = []string{id}
-			request.QueryParams[res.name+"Name"] = []string{linked.Name}
-
-			if source, ok := resource.source.(PaginatedFindAll); ok {
-				// check for pagination, otherwise normal FindAll
-				pagination := newPaginationQueryParams(r)
-				if pagination.isValid() {
-					var count uint
-					count, response, err := source.PaginatedFindAll(request)
-					if err != nil {
-						return err
-					}
-
-					paginationLinks, err := pagination.getLinks(r, count, info)
-					if err != nil {
-						return err
-					}
-
-					return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
-				}
-			}
+	resource, ok := api.findResource(linked.Type)
+	if !ok {
+		return NewHTTPError(
+			errors.New("Not Found"),
+			"No resource handler is registered to handle the linked resource "+linked.Name,
+			http.StatusNotFound,
+		)
+	}
 
-			source, ok := resource.source.(FindAll)
-			if !ok {
-				return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+	r = traceRelation(r, linked.Name)
+	pagination := newPaginationQueryParams(r)
+
+	if finder, ok := resource.source.(PaginatedRelatedFinder); ok && pagination.isValid() {
+		request, span := resource.listRequest(c, r, "PaginatedFindRelated")
+		count, response, err := finder.PaginatedFindRelated(res.name, id, linked.Name, request)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+
+		paginationLinks, err := pagination.getLinks(r, count, info)
+		if err != nil {
+			return err
+		}
+
+		return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
+	}
+
+	if finder, ok := resource.source.(RelatedFinder); ok {
+		request, span := resource.listRequest(c, r, "FindRelated")
+		response, err := finder.FindRelated(res.name, id, linked.Name, request)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+		return res.respondWith(response, info, http.StatusOK, w, r)
+	}
+
+	linkedRequest := func(name string) (Request, Span) {
+		request, span := resource.listRequest(c, r, name)
+		request.QueryParams[res.name+"_id"] = []string{id}
+		request.QueryParams[res.name+"Name"] = []string{linked.Name}
+		return request, span
+	}
+
+	if source, ok := resource.source.(PaginatedFindAll); ok {
+		// check for pagination, otherwise normal FindAll
+		if pagination.isValid() {
+			var count uint
+			request, span := linkedRequest("PaginatedFindAll")
+			count, response, err := source.PaginatedFindAll(request)
+			endSpan(span, err)
+			if err != nil {
+				return err
 			}
 
-			obj, err := source.FindAll(request)
+			paginationLinks, err := pagination.getLinks(r, count, info)
 			if err != nil {
 				return err
 			}
-			return res.respondWith(obj, info, http.StatusOK, w, r)
+
+			return res.respondWithPagination(response, info, http.StatusOK, paginationLinks, w, r)
 		}
 	}
 
-	return NewHTTPError(
-		errors.New("Not Found"),
-		"No resource handler is registered to handle the linked resource "+linked.Name,
-		http.StatusNotFound,
-	)
+	source, ok := resource.source.(FindAll)
+	if !ok {
+		return NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+	}
+
+	request, span := linkedRequest("FindAll")
+	obj, err := source.FindAll(request)
+	endSpan(span, err)
+	if err != nil {
+		return err
+	}
+	return res.respondWith(obj, info, http.StatusOK, w, r)
 }
 
 func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http.Request, prefix string, info information) error {
@@ -647,33 +789,61 @@ func (res *resource) handleCreate(c APIContexter, w http.ResponseWriter, r *http
 		initSource.InitializeObject(newObj)
 	}
 
-	err = jsonapi.Unmarshal(ctx, newObj)
+	if err := res.checkTypes(ctx, newObj); err != nil {
+		return err
+	}
+
+	_, span := startSpan(r, "Unmarshal")
+	err = res.api.info.naming.Unmarshal(ctx, newObj)
+	endSpan(span, err)
 	if err != nil {
 		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
 	}
 
+	var clientID string
+	if identifier, ok := newObj.(jsonapi.MarshalIdentifier); ok {
+		clientID = identifier.GetID()
+	}
+	accepted, err := res.checkClientID(clientID)
+	if err != nil {
+		return err
+	}
+	if acceptor, ok := newObj.(ClientIDAcceptor); ok && accepted {
+		acceptor.AcceptClientID()
+	}
+
 	var response Responder
 
+	request, span := tracedRequest(c, r, "Create")
 	if res.resourceType.Kind() == reflect.Struct {
 		// we have to dereference the pointer if user wants to use non pointer values
-		response, err = source.Create(reflect.ValueOf(newObj).Elem().Interface(), buildRequest(c, r))
+		response, err = source.Create(reflect.ValueOf(newObj).Elem().Interface(), request)
 	} else {
-		response, err = source.Create(newObj, buildRequest(c, r))
+		response, err = source.Create(newObj, request)
 	}
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
 
-	result, ok := response.Result().(jsonapi.MarshalIdentifier)
-
-	if !ok {
+	// 202 and 204 may come without a result, the accepted client id is used
+	// for the location then
+	var id string
+	if result, ok := response.Result().(jsonapi.MarshalIdentifier); ok {
+		id = result.GetID()
+	} else if response.StatusCode() != http.StatusNoContent && response.StatusCode() != http.StatusAccepted {
 		return fmt.Errorf("Expected one newly created object by resource %s", res.name)
 	}
+	if id == "" && accepted {
+		id = clientID
+	}
 
-	if len(prefix) > 0 {
-		w.Header().Set("Location", "/"+prefix+"/"+res.name+"/"+result.GetID())
-	} else {
-		w.Header().Set("Location", "/"+res.name+"/"+result.GetID())
+	if id != "" {
+		if len(prefix) > 0 {
+			w.Header().Set("Location", "/"+prefix+"/"+info.naming.Name(res.name)+"/"+id)
+		} else {
+			w.Header().Set("Location", "/"+info.naming.Name(res.name)+"/"+id)
+		}
 	}
 
 	// handle 200 status codes
@@ -698,7 +868,9 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 		return fmt.Errorf("Resource %s does not implement the ResourceUpdater interface", res.name)
 	}
 	id := params["id"]
-	obj, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	obj, err := source.FindOne(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -708,16 +880,22 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 		return err
 	}
 
+	if err := res.checkTypes(ctx, obj.Result()); err != nil {
+		return err
+	}
+
 	// we have to make the Result to a pointer to unmarshal into it
+	_, span = startSpan(r, "Unmarshal")
 	updatingObj := reflect.ValueOf(obj.Result())
 	if updatingObj.Kind() == reflect.Struct {
 		updatingObjPtr := reflect.New(reflect.TypeOf(obj.Result()))
 		updatingObjPtr.Elem().Set(updatingObj)
-		err = jsonapi.Unmarshal(ctx, updatingObjPtr.Interface())
+		err = res.api.info.naming.Unmarshal(ctx, updatingObjPtr.Interface())
 		updatingObj = updatingObjPtr.Elem()
 	} else {
-		err = jsonapi.Unmarshal(ctx, updatingObj.Interface())
+		err = res.api.info.naming.Unmarshal(ctx, updatingObj.Interface())
 	}
+	endSpan(span, err)
 	if err != nil {
 		return NewHTTPError(nil, err.Error(), http.StatusNotAcceptable)
 	}
@@ -728,7 +906,9 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 		return NewHTTPError(conflictError, conflictError.Error(), http.StatusConflict)
 	}
 
-	response, err := source.Update(updatingObj.Interface(), buildRequest(c, r))
+	request, span = tracedRequest(c, r, "Update")
+	response, err := source.Update(updatingObj.Interface(), request)
+	endSpan(span, err)
 
 	if err != nil {
 		return err
@@ -738,7 +918,9 @@ func (res *resource) handleUpdate(c APIContexter, w http.ResponseWriter, r *http
 	case http.StatusOK:
 		updated := response.Result()
 		if updated == nil {
-			internalResponse, err := source.FindOne(id, buildRequest(c, r))
+			request, span := tracedRequest(c, r, "Refetch")
+			internalResponse, err := source.FindOne(id, request)
+			endSpan(span, err)
 			if err != nil {
 				return err
 			}
@@ -775,8 +957,11 @@ func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter
 	)
 
 	id := params["id"]
+	r = traceRelation(r, relation.Name)
 
-	response, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	response, err := source.FindOne(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -786,6 +971,10 @@ func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter
 		return err
 	}
 
+	if err := res.checkLinkageTypes(body, relation); err != nil {
+		return err
+	}
+
 	inc := map[string]interface{}{}
 	err = jsonLib.Unmarshal(body, &inc)
 	if err != nil {
@@ -808,11 +997,13 @@ func (res *resource) handleReplaceRelation(c APIContexter, w http.ResponseWriter
 		return err
 	}
 
+	request, span = tracedRequest(c, r, "Update")
 	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(editObj).Elem().Interface(), buildRequest(c, r))
+		_, err = source.Update(reflect.ValueOf(editObj).Elem().Interface(), request)
 	} else {
-		_, err = source.Update(editObj, buildRequest(c, r))
+		_, err = source.Update(editObj, request)
 	}
+	endSpan(span, err)
 
 	w.WriteHeader(http.StatusNoContent)
 	return err
@@ -830,8 +1021,11 @@ func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWrit
 	)
 
 	id := params["id"]
+	r = traceRelation(r, relation.Name)
 
-	response, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	response, err := source.FindOne(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -840,6 +1034,10 @@ func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWrit
 	if err != nil {
 		return err
 	}
+
+	if err := res.checkLinkageTypes(body, relation); err != nil {
+		return err
+	}
 	inc := map[string]interface{}{}
 	err = jsonLib.Unmarshal(body, &inc)
 	if err != nil {
@@ -884,11 +1082,13 @@ func (res *resource) handleAddToManyRelation(c APIContexter, w http.ResponseWrit
 	}
 	targetObj.AddToManyIDs(relation.Name, newIDs)
 
+	request, span = tracedRequest(c, r, "Update")
 	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
+		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), request)
 	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+		_, err = source.Update(targetObj, request)
 	}
+	endSpan(span, err)
 
 	w.WriteHeader(http.StatusNoContent)
 
@@ -907,8 +1107,11 @@ func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseW
 	)
 
 	id := params["id"]
+	r = traceRelation(r, relation.Name)
 
-	response, err := source.FindOne(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "FindOne")
+	response, err := source.FindOne(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -918,6 +1121,10 @@ func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseW
 		return err
 	}
 
+	if err := res.checkLinkageTypes(body, relation); err != nil {
+		return err
+	}
+
 	inc := map[string]interface{}{}
 	err = jsonLib.Unmarshal(body, &inc)
 	if err != nil {
@@ -962,11 +1169,13 @@ func (res *resource) handleDeleteToManyRelation(c APIContexter, w http.ResponseW
 	}
 	targetObj.DeleteToManyIDs(relation.Name, obsoleteIDs)
 
+	request, span = tracedRequest(c, r, "Update")
 	if resType == reflect.Struct {
-		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), buildRequest(c, r))
+		_, err = source.Update(reflect.ValueOf(targetObj).Elem().Interface(), request)
 	} else {
-		_, err = source.Update(targetObj, buildRequest(c, r))
+		_, err = source.Update(targetObj, request)
 	}
+	endSpan(span, err)
 
 	w.WriteHeader(http.StatusNoContent)
 
@@ -988,7 +1197,9 @@ func (res *resource) handleDelete(c APIContexter, w http.ResponseWriter, r *http
 		return fmt.Errorf("Resource %s does not implement the ResourceDeleter interface", res.name)
 	}
 	id := params["id"]
-	response, err := source.Delete(id, buildRequest(c, r))
+	request, span := tracedRequest(c, r, "Delete")
+	response, err := source.Delete(id, request)
+	endSpan(span, err)
 	if err != nil {
 		return err
 	}
@@ -1017,7 +1228,10 @@ func writeResult(w http.ResponseWriter, data []byte, status int, contentType str
 	w.Write(data)
 }
 
-func (res *resource) respondWith(obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) error {
+func (res *resource) respondWith(obj Responder, info information, status int, w http.ResponseWriter, r *http.Request) (err error) {
+	_, span := startSpan(r, "Marshal")
+	defer func() { endSpan(span, err) }()
+
 	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
 	if err != nil {
 		return err
@@ -1040,7 +1254,10 @@ func (res *resource) respondWith(obj Responder, info information, status int, w
 	return res.marshalResponse(data, w, status, r)
 }
 
-func (res *resource) respondWithPagination(obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) error {
+func (res *resource) respondWithPagination(obj Responder, info information, status int, links jsonapi.Links, w http.ResponseWriter, r *http.Request) (err error) {
+	_, span := startSpan(r, "Marshal")
+	defer func() { endSpan(span, err) }()
+
 	data, err := jsonapi.MarshalToStruct(obj.Result(), info)
 	if err != nil {
 		return err
@@ -1055,6 +1272,41 @@ func (res *resource) respondWithPagination(obj Responder, info information, stat
 	return res.marshalResponse(data, w, status, r)
 }
 
+// checkTypes answers with 409 Conflict if strict type checking is enabled and
+// a type in the document does not match the resource or its relationships.
+// Malformed documents are left to the unmarshalling which follows.
+func (res *resource) checkTypes(body []byte, target interface{}) error {
+	if !res.api.strictTypes {
+		return nil
+	}
+	naming := res.api.info.naming
+	return typeConflict(naming.CheckTypes(body, naming.Name(res.name), target))
+}
+
+// checkLinkageTypes does the same as checkTypes for relationship documents
+func (res *resource) checkLinkageTypes(body []byte, relation jsonapi.Reference) error {
+	if !res.api.strictTypes {
+		return nil
+	}
+	return typeConflict(jsonapi.CheckLinkageTypes(body, res.api.info.naming.Name(relation.Type)))
+}
+
+func typeConflict(err error) error {
+	var conflict jsonapi.TypeConflictError
+	if !errors.As(err, &conflict) {
+		return nil
+	}
+	httpError := NewHTTPError(err, conflict.Error(), http.StatusConflict)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(http.StatusConflict),
+		Code:   codeTypeConflict,
+		Title:  "Type conflict",
+		Detail: conflict.Error(),
+		Source: &ErrorSource{Pointer: conflict.Pointer},
+	})
+	return httpError
+}
+
 func unmarshalRequest(r *http.Request) ([]byte, error) {
 	defer r.Body.Close()
 	data, err := ioutil.ReadAll(r.Body)
@@ -1172,15 +1424,12 @@ func replaceAttributes(query *map[string][]string, entry *jsonapi.Data) map[stri
 }
 
 func handleError(err error, w http.ResponseWriter, r *http.Request, contentType string) {
-	log.Println(err)
-	if e, ok := err.(HTTPError); ok {
-		writeResult(w, []byte(marshalHTTPError(e)), e.status, contentType)
-		return
-
-	}
+	errorHandling{}.handle(err, w, r, contentType)
+}
 
-	e := NewHTTPError(err, err.Error(), http.StatusInternalServerError)
-	writeResult(w, []byte(marshalHTTPError(e)), http.StatusInternalServerError, contentType)
+// handleError writes err with the mappers, mode and logger of the api
+func (api *API) handleError(err error, w http.ResponseWriter, r *http.Request) {
+	api.errorHandling.handle(err, w, r, api.ContentType)
 }
 
 // TODO: this can also be replaced with a struct into that we directly json.Unmarshal
@@ -0,0 +1,251 @@
+// Package api2gotest sends JSON:API requests to an api2go API in tests and
+// checks the answers, without starting a server.
+//
+//	c := api2gotest.New(t, api)
+//	user := c.Create("users", map[string]interface{}{"name": "Ann"}, nil).
+//		ExpectStatus(http.StatusCreated)
+//	c.Get("users", user.ID(), api2gotest.Include("posts")).
+//		ExpectStatus(http.StatusOK).
+//		ExpectIncluded("posts", "1")
+package api2gotest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+const contentType = "application/vnd.api+json"
+
+// Client sends requests to the handler of an API
+type Client struct {
+	t       testing.TB
+	handler http.Handler
+	prefix  string
+
+	// Header is sent with every request
+	Header http.Header
+}
+
+// New returns a client for the handler of api
+func New(t testing.TB, api *api2go.API) *Client {
+	return NewWithHandler(t, api.Handler(), api.Prefix())
+}
+
+// NewWithHandler returns a client for any handler which serves resources below
+// prefix, e.g. an API wrapped in middlewares
+func NewWithHandler(t testing.TB, handler http.Handler, prefix string) *Client {
+	return &Client{t: t, handler: handler, prefix: prefix, Header: make(http.Header)}
+}
+
+// Option changes the query of a Get or List request
+type Option func(query url.Values)
+
+// Include asks for related resources to be included
+func Include(paths ...string) Option {
+	return func(query url.Values) {
+		query.Set("include", strings.Join(paths, ","))
+	}
+}
+
+// Fields asks for a sparse fieldset of resourceType
+func Fields(resourceType string, fields ...string) Option {
+	return func(query url.Values) {
+		query.Set("fields["+resourceType+"]", strings.Join(fields, ","))
+	}
+}
+
+// Sort sets the sort parameter, prefix a field with "-" for descending order
+func Sort(fields ...string) Option {
+	return func(query url.Values) {
+		query.Set("sort", strings.Join(fields, ","))
+	}
+}
+
+// Filter adds a filter[field] parameter
+func Filter(field string, values ...string) Option {
+	return func(query url.Values) {
+		query.Set("filter["+field+"]", strings.Join(values, ","))
+	}
+}
+
+// Page sets page[key], e.g. Page("number", "2")
+func Page(key, value string) Option {
+	return func(query url.Values) {
+		query.Set("page["+key+"]", value)
+	}
+}
+
+// Query sets any other query parameter
+func Query(key, value string) Option {
+	return func(query url.Values) {
+		query.Set(key, value)
+	}
+}
+
+// ToOne returns a to-one relationship, call it without id to clear the relationship
+func ToOne(resourceType string, id ...string) jsonapi.Relationship {
+	if len(id) == 0 {
+		return jsonapi.Relationship{Data: &jsonapi.RelationshipDataContainer{}}
+	}
+	return jsonapi.Relationship{Data: &jsonapi.RelationshipDataContainer{
+		DataObject: &jsonapi.RelationshipData{Type: resourceType, ID: id[0]},
+	}}
+}
+
+// ToMany returns a to-many relationship
+func ToMany(resourceType string, ids ...string) jsonapi.Relationship {
+	data := make([]jsonapi.RelationshipData, 0, len(ids))
+	for _, id := range ids {
+		data = append(data, jsonapi.RelationshipData{Type: resourceType, ID: id})
+	}
+	return jsonapi.Relationship{Data: &jsonapi.RelationshipDataContainer{DataArray: data}}
+}
+
+func (c *Client) path(segments ...string) string {
+	path := "/" + strings.Trim(c.prefix, "/")
+	for _, segment := range segments {
+		if path != "/" {
+			path += "/"
+		}
+		path += segment
+	}
+	return path
+}
+
+func resourceDocument(resourceType, id string, attributes map[string]interface{}, relationships map[string]jsonapi.Relationship) map[string]interface{} {
+	data := map[string]interface{}{"type": resourceType}
+	if id != "" {
+		data["id"] = id
+	}
+	if attributes != nil {
+		data["attributes"] = attributes
+	}
+	if relationships != nil {
+		data["relationships"] = relationships
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// Do sends a request to path, which is relative to the prefix of the API.
+// A body which is not a []byte, string or nil is encoded as JSON.
+func (c *Client) Do(method, path string, body interface{}) *Response {
+	c.t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(b)
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			c.t.Fatalf("cannot encode request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = c.path(path)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if reader != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	recorder := httptest.NewRecorder()
+	c.handler.ServeHTTP(recorder, req)
+	return newResponse(c.t, method+" "+path, recorder.Result())
+}
+
+func withOptions(path string, options []Option) string {
+	query := url.Values{}
+	for _, option := range options {
+		option(query)
+	}
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// Create posts a new resource
+func (c *Client) Create(resourceType string, attributes map[string]interface{}, relationships map[string]jsonapi.Relationship) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, c.path(resourceType), resourceDocument(resourceType, "", attributes, relationships))
+}
+
+// CreateWithID posts a new resource with a client generated id
+func (c *Client) CreateWithID(resourceType, id string, attributes map[string]interface{}, relationships map[string]jsonapi.Relationship) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, c.path(resourceType), resourceDocument(resourceType, id, attributes, relationships))
+}
+
+// Get fetches a single resource
+func (c *Client) Get(resourceType, id string, options ...Option) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, withOptions(c.path(resourceType, id), options), nil)
+}
+
+// List fetches a collection
+func (c *Client) List(resourceType string, options ...Option) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, withOptions(c.path(resourceType), options), nil)
+}
+
+// Related fetches the resources linked by a relationship
+func (c *Client) Related(resourceType, id, relationship string, options ...Option) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, withOptions(c.path(resourceType, id, relationship), options), nil)
+}
+
+// Relationship fetches the linkage of a relationship
+func (c *Client) Relationship(resourceType, id, relationship string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodGet, c.path(resourceType, id, "relationships", relationship), nil)
+}
+
+// Update patches a resource, only the given attributes and relationships are changed
+func (c *Client) Update(resourceType, id string, attributes map[string]interface{}, relationships map[string]jsonapi.Relationship) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPatch, c.path(resourceType, id), resourceDocument(resourceType, id, attributes, relationships))
+}
+
+// Delete deletes a resource
+func (c *Client) Delete(resourceType, id string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, c.path(resourceType, id), nil)
+}
+
+// ReplaceRelationship replaces the linkage of a to-one or to-many relationship
+func (c *Client) ReplaceRelationship(resourceType, id, relationship string, linkage jsonapi.Relationship) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPatch, c.path(resourceType, id, "relationships", relationship), linkage)
+}
+
+// AddToMany adds members to a to-many relationship
+func (c *Client) AddToMany(resourceType, id, relationship, relatedType string, relatedIDs ...string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodPost, c.path(resourceType, id, "relationships", relationship), ToMany(relatedType, relatedIDs...))
+}
+
+// DeleteFromMany removes members from a to-many relationship
+func (c *Client) DeleteFromMany(resourceType, id, relationship, relatedType string, relatedIDs ...string) *Response {
+	c.t.Helper()
+	return c.Do(http.MethodDelete, c.path(resourceType, id, "relationships", relationship), ToMany(relatedType, relatedIDs...))
+}
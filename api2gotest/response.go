@@ -0,0 +1,247 @@
+package api2gotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// Response is a decoded answer of the API. All Expect methods report
+// failures with t.Errorf and return the response, so they can be chained.
+type Response struct {
+	t       testing.TB
+	request string
+
+	Status int
+	Header http.Header
+	Body   []byte
+
+	// Document is nil if the body has no "data" member
+	Document *jsonapi.Document
+	Errors   []api2go.Error
+}
+
+func newResponse(t testing.TB, request string, res *http.Response) *Response {
+	t.Helper()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatalf("%s: cannot read response: %v", request, err)
+	}
+	r := &Response{t: t, request: request, Status: res.StatusCode, Header: res.Header, Body: body}
+	if len(body) == 0 {
+		return r
+	}
+
+	var probe struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []api2go.Error  `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &probe); err != nil {
+		t.Errorf("%s: response is not JSON: %v\n%s", request, err, body)
+		return r
+	}
+	r.Errors = probe.Errors
+	if len(probe.Data) > 0 {
+		document := &jsonapi.Document{}
+		if string(probe.Data) == "null" {
+			document.Data = &jsonapi.DataContainer{}
+		} else if err := json.Unmarshal(body, document); err != nil {
+			t.Errorf("%s: response is not a JSON:API document: %v\n%s", request, err, body)
+			return r
+		}
+		r.Document = document
+	}
+	return r
+}
+
+// ExpectStatus checks the HTTP status code
+func (r *Response) ExpectStatus(status int) *Response {
+	r.t.Helper()
+	if r.Status != status {
+		r.t.Errorf("%s: expected status %d, got %d\n%s", r.request, status, r.Status, r.Body)
+	}
+	return r
+}
+
+// ExpectHeader checks the value of a response header
+func (r *Response) ExpectHeader(name, value string) *Response {
+	r.t.Helper()
+	if got := r.Header.Get(name); got != value {
+		r.t.Errorf("%s: expected header %s to be %q, got %q", r.request, name, value, got)
+	}
+	return r
+}
+
+// ExpectErrorCode checks that one of the errors has the given code
+func (r *Response) ExpectErrorCode(code string) *Response {
+	r.t.Helper()
+	for _, e := range r.Errors {
+		if e.Code == code {
+			return r
+		}
+	}
+	r.t.Errorf("%s: expected an error with code %q\n%s", r.request, code, r.Body)
+	return r
+}
+
+// ExpectErrorPointer checks that one of the errors has the given source pointer
+func (r *Response) ExpectErrorPointer(pointer string) *Response {
+	r.t.Helper()
+	for _, e := range r.Errors {
+		if e.Source != nil && e.Source.Pointer == pointer {
+			return r
+		}
+	}
+	r.t.Errorf("%s: expected an error with source pointer %q\n%s", r.request, pointer, r.Body)
+	return r
+}
+
+// ExpectErrorParameter checks that one of the errors has the given source parameter
+func (r *Response) ExpectErrorParameter(parameter string) *Response {
+	r.t.Helper()
+	for _, e := range r.Errors {
+		if e.Source != nil && e.Source.Parameter == parameter {
+			return r
+		}
+	}
+	r.t.Errorf("%s: expected an error with source parameter %q\n%s", r.request, parameter, r.Body)
+	return r
+}
+
+// ExpectCount checks the number of resources in the primary data
+func (r *Response) ExpectCount(count int) *Response {
+	r.t.Helper()
+	if got := len(r.Items()); got != count {
+		r.t.Errorf("%s: expected %d resources, got %d\n%s", r.request, count, got, r.Body)
+	}
+	return r
+}
+
+// ExpectIncluded checks that the resource is in the included member
+func (r *Response) ExpectIncluded(resourceType, id string) *Response {
+	r.t.Helper()
+	if r.Included(resourceType, id) == nil {
+		r.t.Errorf("%s: expected %s %s to be included\n%s", r.request, resourceType, id, r.Body)
+	}
+	return r
+}
+
+// ExpectLink checks that the top level links contain name, e.g. "next_page_url"
+func (r *Response) ExpectLink(name string) *Response {
+	r.t.Helper()
+	if r.Link(name) == "" {
+		r.t.Errorf("%s: expected link %q\n%s", r.request, name, r.Body)
+	}
+	return r
+}
+
+// ExpectNoLink checks that the top level links do not contain name
+func (r *Response) ExpectNoLink(name string) *Response {
+	r.t.Helper()
+	if r.Link(name) != "" {
+		r.t.Errorf("%s: expected no link %q\n%s", r.request, name, r.Body)
+	}
+	return r
+}
+
+// ExpectAttribute checks the value of an attribute of the single primary resource.
+// Values are compared by their JSON encoding, so 1 and 1.0 are equal.
+func (r *Response) ExpectAttribute(name string, value interface{}) *Response {
+	r.t.Helper()
+	got, _ := json.Marshal(r.Attribute(name))
+	want, _ := json.Marshal(value)
+	if string(got) != string(want) {
+		r.t.Errorf("%s: expected attribute %s to be %s, got %s", r.request, name, want, got)
+	}
+	return r
+}
+
+// Data returns the single primary resource, the test fails if there is none
+func (r *Response) Data() *jsonapi.Data {
+	r.t.Helper()
+	if r.Document == nil || r.Document.Data == nil || r.Document.Data.DataObject == nil {
+		r.t.Fatalf("%s: response has no single resource\n%s", r.request, r.Body)
+	}
+	return r.Document.Data.DataObject
+}
+
+// Items returns the resources of the primary data
+func (r *Response) Items() []jsonapi.Data {
+	if r.Document == nil || r.Document.Data == nil {
+		return nil
+	}
+	if r.Document.Data.DataObject != nil {
+		return []jsonapi.Data{*r.Document.Data.DataObject}
+	}
+	return r.Document.Data.DataArray
+}
+
+// ID returns the id of the single primary resource
+func (r *Response) ID() string {
+	r.t.Helper()
+	return r.Data().ID
+}
+
+// Attributes decodes the attributes of the single primary resource
+func (r *Response) Attributes() map[string]interface{} {
+	r.t.Helper()
+	attributes := make(map[string]interface{})
+	if raw := r.Data().Attributes; len(raw) > 0 {
+		if err := json.Unmarshal(raw, &attributes); err != nil {
+			r.t.Fatalf("%s: cannot decode attributes: %v", r.request, err)
+		}
+	}
+	return attributes
+}
+
+// Attribute returns one attribute of the single primary resource
+func (r *Response) Attribute(name string) interface{} {
+	r.t.Helper()
+	return r.Attributes()[name]
+}
+
+// Included returns the included resource or nil
+func (r *Response) Included(resourceType, id string) *jsonapi.Data {
+	if r.Document == nil {
+		return nil
+	}
+	for i, included := range r.Document.Included {
+		if included.Type == resourceType && included.ID == id {
+			return &r.Document.Included[i]
+		}
+	}
+	return nil
+}
+
+// Link returns a top level link as string, links objects are reduced to their href
+func (r *Response) Link(name string) string {
+	if r.Document == nil {
+		return ""
+	}
+	switch link := r.Document.Links[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return link
+	case map[string]interface{}:
+		if href, ok := link["href"].(string); ok {
+			return href
+		}
+	}
+	return fmt.Sprintf("%v", r.Document.Links[name])
+}
+
+// Decode unmarshals the document into target using jsonapi.Unmarshal
+func (r *Response) Decode(target interface{}) *Response {
+	r.t.Helper()
+	if err := jsonapi.Unmarshal(r.Body, target); err != nil {
+		r.t.Errorf("%s: cannot unmarshal response: %v", r.request, err)
+	}
+	return r
+}
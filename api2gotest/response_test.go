@@ -0,0 +1,125 @@
+package api2gotest_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go/v2/api2gotest"
+)
+
+// fakeT records the failures reported by the assertions
+type fakeT struct {
+	testing.TB
+	failures []string
+}
+
+// fatal ends the assertion which called Fatalf
+type fatal struct{}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failures = append(f.failures, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.Errorf(format, args...)
+	panic(fatal{})
+}
+
+// failures returns the failures check reports for a response with the given
+// status, headers and body
+func failures(status int, header http.Header, body string, check func(r *api2gotest.Response)) []string {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+	t := &fakeT{}
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				if _, ok := r.(fatal); !ok {
+					panic(r)
+				}
+			}
+		}()
+		check(api2gotest.NewWithHandler(t, handler, "/v1").Get("user", "1"))
+	}()
+	return t.failures
+}
+
+const (
+	listBody = `{
+		"data": [{"type": "user", "id": "1", "attributes": {"name": "ada"}}],
+		"included": [{"type": "tag", "id": "t1"}],
+		"links": {"next_page_url": "/v1/user?page[number]=2", "self": {"href": "/v1/user"}}
+	}`
+	singleBody = `{"data": {"type": "user", "id": "1", "attributes": {"name": "ada", "age": 36}}}`
+	errorBody  = `{"errors": [{"status": "422", "code": "invalid", "source": {"pointer": "/data/attributes/age", "parameter": "sort"}}]}`
+)
+
+func TestAssertions(t *testing.T) {
+	header := http.Header{"Content-Type": {"application/vnd.api+json"}}
+
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(r *api2gotest.Response)
+		fails  bool
+	}{
+		{"status", 200, listBody, func(r *api2gotest.Response) { r.ExpectStatus(200) }, false},
+		{"wrong status", 404, listBody, func(r *api2gotest.Response) { r.ExpectStatus(200) }, true},
+		{"header", 200, listBody, func(r *api2gotest.Response) { r.ExpectHeader("Content-Type", "application/vnd.api+json") }, false},
+		{"wrong header", 200, listBody, func(r *api2gotest.Response) { r.ExpectHeader("Content-Type", "text/plain") }, true},
+		{"missing header", 200, listBody, func(r *api2gotest.Response) { r.ExpectHeader("Location", "/v1/user/1") }, true},
+		{"error code", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorCode("invalid") }, false},
+		{"wrong error code", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorCode("conflict") }, true},
+		{"error code without errors", 200, listBody, func(r *api2gotest.Response) { r.ExpectErrorCode("invalid") }, true},
+		{"error pointer", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorPointer("/data/attributes/age") }, false},
+		{"wrong error pointer", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorPointer("/data/attributes/name") }, true},
+		{"error parameter", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorParameter("sort") }, false},
+		{"wrong error parameter", 422, errorBody, func(r *api2gotest.Response) { r.ExpectErrorParameter("filter") }, true},
+		{"count", 200, listBody, func(r *api2gotest.Response) { r.ExpectCount(1) }, false},
+		{"wrong count", 200, listBody, func(r *api2gotest.Response) { r.ExpectCount(2) }, true},
+		{"included", 200, listBody, func(r *api2gotest.Response) { r.ExpectIncluded("tag", "t1") }, false},
+		{"not included", 200, listBody, func(r *api2gotest.Response) { r.ExpectIncluded("tag", "t2") }, true},
+		{"included of another type", 200, listBody, func(r *api2gotest.Response) { r.ExpectIncluded("user", "t1") }, true},
+		{"link", 200, listBody, func(r *api2gotest.Response) { r.ExpectLink("next_page_url") }, false},
+		{"links object", 200, listBody, func(r *api2gotest.Response) { r.ExpectLink("self") }, false},
+		{"missing link", 200, listBody, func(r *api2gotest.Response) { r.ExpectLink("prev_page_url") }, true},
+		{"no link", 200, listBody, func(r *api2gotest.Response) { r.ExpectNoLink("prev_page_url") }, false},
+		{"unexpected link", 200, listBody, func(r *api2gotest.Response) { r.ExpectNoLink("next_page_url") }, true},
+		{"attribute", 200, singleBody, func(r *api2gotest.Response) { r.ExpectAttribute("age", 36) }, false},
+		{"wrong attribute", 200, singleBody, func(r *api2gotest.Response) { r.ExpectAttribute("age", "36") }, true},
+		{"attribute of a list", 200, listBody, func(r *api2gotest.Response) { r.ExpectAttribute("name", "ada") }, true},
+		{"not json", 200, "<html></html>", func(r *api2gotest.Response) {}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := failures(test.status, header, test.body, test.check)
+			if test.fails && len(got) == 0 {
+				t.Error("expected the assertion to fail")
+			}
+			if !test.fails && len(got) > 0 {
+				t.Errorf("expected the assertion to pass, got %v", got)
+			}
+		})
+	}
+}
+
+func TestAssertionsReportTheRequest(t *testing.T) {
+	got := failures(404, nil, errorBody, func(r *api2gotest.Response) { r.ExpectStatus(200) })
+	if len(got) != 1 {
+		t.Fatalf("expected one failure, got %v", got)
+	}
+	if want := "GET /v1/user/1: expected status 200, got 404"; !strings.HasPrefix(got[0], want) {
+		t.Errorf("expected the failure to start with %q, got %q", want, got[0])
+	}
+}
@@ -84,6 +84,22 @@ type FindAll interface {
 	FindAll(req Request) (Responder, error)
 }
 
+// The RelatedFinder interface can be optionally implemented to fetch the
+// resources related to another resource, for the /parent/:id/relation and
+// /parent/:id/relationships/relation routes. parentType and relationName
+// identify the relationship on the parent's side. If it is not implemented,
+// FindAll is called with the parent given in the "<parentType>_id" and
+// "<parentType>Name" query parameters instead.
+type RelatedFinder interface {
+	FindRelated(parentType, parentID, relationName string, req Request) (Responder, error)
+}
+
+// The PaginatedRelatedFinder interface is the paginated variant of
+// RelatedFinder, it is used when the request has pagination query parameters.
+type PaginatedRelatedFinder interface {
+	PaginatedFindRelated(parentType, parentID, relationName string, req Request) (totalCount uint, response Responder, err error)
+}
+
 // The ObjectInitializer interface can be implemented to have the ability to change
 // a created object before Unmarshal is called. This is currently only called on
 // Create as the other actions go through FindOne or FindAll which are already
@@ -1,6 +1,7 @@
 package api2go
 
 import (
+	"fmt"
 	"github.com/artpar/api2go/v2/jsonapi"
 	"github.com/artpar/api2go/v2/routing"
 	"net/http"
@@ -20,18 +21,73 @@ type API struct {
 	middlewares      []HandlerFunc
 	contextPool      sync.Pool
 	contextAllocator APIContextAllocatorFunc
+	strictTypes      bool
+	clientIDPolicies map[string]ClientIDPolicy
+	cors             *CORSConfig
+	rateLimits       map[string]map[Operation]RateLimit
+	rateLimitStore   RateLimitStore
+	errorHandling    errorHandling
+	panicHandler     PanicHandler
+	observers        []Observer
+	tracer           Tracer
+	accessLog        *AccessLogConfig
+	version          string
+	deprecation      *Deprecation
+	// resourceDeprecations override the deprecation per resource
+	resourceDeprecations map[string]Deprecation
+	routes               []routeEntry
+	// resourceMutex guards resources and routes, changeMutex serializes
+	// adding, removing and replacing resources
+	resourceMutex sync.RWMutex
+	changeMutex   sync.Mutex
+	// staged holds the changes of the running batch
+	staged *resourceState
 }
 
 // Handler returns the http.Handler instance for the API.
-func (api API) Handler() http.Handler {
+func (api *API) Handler() http.Handler {
 	return api.router.Handler()
 }
 
-// Router returns the specified router on an api instance
-func (api API) Router() routing.Routeable {
+// Router returns the specified router on an api instance. APIs which were not
+// created with NewAPIWithRouting use a *routing.Dispatcher.
+func (api *API) Router() routing.Routeable {
 	return api.router
 }
 
+// Prefix returns the prefix which was given when the API was created
+func (api *API) Prefix() string {
+	return api.info.GetPrefix()
+}
+
+// SetStrictTypeChecking enables or disables strict type checking. If enabled,
+// documents whose type does not match the resource, or whose relationship
+// linkage does not match the type of the relationship, are answered with
+// 409 Conflict and a source pointer to the offending type.
+func (api *API) SetStrictTypeChecking(enabled bool) {
+	api.strictTypes = enabled
+}
+
+// SetNamingStrategy converts type names, attribute keys and relationship
+// names with strategy, in documents as well as in the generated routes.
+// Resources keep using their own names. It must be called before resources
+// are added.
+func (api *API) SetNamingStrategy(strategy jsonapi.NamingStrategy) {
+	if len(api.resourceList()) > 0 {
+		panic("the naming strategy must be set before resources are added")
+	}
+	api.info.naming = strategy
+}
+
+// memberNameError reports the members of prototype which the naming strategy
+// turns into names JSON:API reserves
+func (api *API) memberNameError(prototype jsonapi.MarshalIdentifier) error {
+	if err := api.info.naming.CheckMemberNames(prototype); err != nil {
+		return fmt.Errorf("resource %s: %w", resourceName(prototype), err)
+	}
+	return nil
+}
+
 // SetContextAllocator custom implementation for making contexts
 func (api *API) SetContextAllocator(allocator APIContextAllocatorFunc) {
 	api.contextAllocator = allocator
@@ -42,7 +98,27 @@ func (api *API) SetContextAllocator(allocator APIContextAllocatorFunc) {
 // `resource` should be either an empty struct instance such as `Post{}` or a pointer to
 // a struct such as `&Post{}`. The same type will be used for constructing new elements.
 func (api *API) AddResource(prototype jsonapi.MarshalIdentifier, source interface{}) {
-	api.addResource(prototype, source)
+	if err := api.TryAddResource(prototype, source); err != nil {
+		panic(err)
+	}
+}
+
+// TryAddResource registers a resource like AddResource, but returns the
+// *RelationError of invalid relations, members named type or id by the naming
+// strategy or the error of conflicting routes instead of panicking.
+func (api *API) TryAddResource(prototype jsonapi.MarshalIdentifier, source interface{}) error {
+	api.changeMutex.Lock()
+	defer api.changeMutex.Unlock()
+	if err := api.memberNameError(prototype); err != nil {
+		return err
+	}
+	if err := api.checkRelations(resourceName(prototype), prototype); err != nil {
+		return err
+	}
+	return api.batch(func() error {
+		api.addResource(prototype, source)
+		return nil
+	})
 }
 
 // UseMiddleware registers middlewares that implement the api2go.HandlerFunc
@@ -61,7 +137,7 @@ func (api *API) NewAPIVersion(prefix string) *API {
 // NewAPIWithResolver can be used to create an API with a custom URL resolver.
 func NewAPIWithResolver(prefix string, resolver URLResolver) *API {
 	handler := notAllowedHandler{}
-	r := routing.NewHTTPRouter(prefix, &handler)
+	r := newDefaultRouter(prefix, &handler)
 	api := newAPI(prefix, resolver, r)
 	handler.API = api
 	return api
@@ -73,7 +149,7 @@ func NewAPIWithResolver(prefix string, resolver URLResolver) *API {
 func NewAPIWithBaseURL(prefix string, baseURL string) *API {
 	handler := notAllowedHandler{}
 	staticResolver := NewStaticResolver(baseURL)
-	r := routing.NewHTTPRouter(prefix, &handler)
+	r := newDefaultRouter(prefix, &handler)
 	api := newAPI(prefix, staticResolver, r)
 	handler.API = api
 	return api
@@ -84,7 +160,7 @@ func NewAPIWithBaseURL(prefix string, baseURL string) *API {
 func NewAPI(prefix string) *API {
 	handler := notAllowedHandler{}
 	staticResolver := NewStaticResolver("")
-	r := routing.NewHTTPRouter(prefix, &handler)
+	r := newDefaultRouter(prefix, &handler)
 	api := newAPI(prefix, staticResolver, r)
 	handler.API = api
 	return api
@@ -20,7 +20,7 @@ type TableRelationInterface interface {
 type TableRelation struct {
 	Subject     string
 	Object      string
-	Relation    string
+	Relation    RelationKind
 	SubjectName string
 	ObjectName  string
 	Columns     []ColumnInfo
@@ -49,6 +49,22 @@ func (tr *TableRelation) GetJoinTableName() string {
 	return tr.Subject + "_" + tr.GetSubjectName() + "_has_" + tr.Object + "_" + tr.GetObjectName()
 }
 
+// GetJoinColumnNames returns the columns of the join table holding the ids of
+// the subject and of the object. They are named like the subject and the
+// object, the object column gets the suffix "_2" if both names are the same,
+// as in self relations with default names.
+func (tr *TableRelation) GetJoinColumnNames() (string, string) {
+	subject, object := tr.GetSubjectName(), tr.GetObjectName()
+	if object == subject {
+		object += "_2"
+	}
+	return subject, object
+}
+
+// GetJoinString returns the unquoted join fragment from the subject to the object.
+//
+// Deprecated: use JoinClauses together with a SelectQuery, which quotes identifiers
+// for the target Dialect.
 func (tr *TableRelation) GetJoinString() string {
 
 	if tr.Relation == "has_one" {
@@ -70,13 +86,17 @@ func (tr *TableRelation) GetJoinString() string {
 	return ""
 }
 
+// GetReverseJoinString returns the unquoted join fragment from the object to the subject.
+//
+// Deprecated: use ReverseJoinClauses together with a SelectQuery, which quotes
+// identifiers for the target Dialect.
 func (tr *TableRelation) GetReverseJoinString() string {
 
 	if tr.Relation == "has_one" {
 		return fmt.Sprintf(" %s %s on %s.%s = %s.%s ", tr.GetSubject(), tr.GetSubjectName(), tr.GetSubjectName(), tr.GetObjectName(), tr.GetObject(), "id")
 	} else if tr.Relation == "belongs_to" {
 		return fmt.Sprintf(" %s %s on %s.%s = %s.%s ", tr.GetSubject(), tr.GetSubjectName(), tr.GetSubjectName(), tr.GetObjectName(), tr.GetObject(), "id")
-	} else if tr.Relation == "has_many" {
+	} else if tr.Relation == "has_many" || tr.Relation == "has_many_and_belongs_to_many" {
 
 		//select * from user join user_has_usergroup j1 on j1.user_id = user.id  join usergroup on j1.usergroup_id = usergroup.id
 		return fmt.Sprintf(" %s %s on %s.%s = %s.id join %s %s on %s.%s = %s.%s ",
@@ -94,7 +114,7 @@ func (tr *TableRelation) GetReverseJoinString() string {
 }
 
 func (tr *TableRelation) GetRelation() string {
-	return tr.Relation
+	return string(tr.Relation)
 }
 
 func (tr *TableRelation) GetObjectName() string {
@@ -108,7 +128,7 @@ func (tr *TableRelation) GetObject() string {
 	return tr.Object
 }
 
-func NewTableRelation(subject, relation, object string) TableRelation {
+func NewTableRelation(subject string, relation RelationKind, object string) TableRelation {
 	return TableRelation{
 		Subject:     subject,
 		Relation:    relation,
@@ -118,7 +138,7 @@ func NewTableRelation(subject, relation, object string) TableRelation {
 	}
 }
 
-func NewTableRelationWithNames(subject, subjectName, relation, object, objectName string) TableRelation {
+func NewTableRelationWithNames(subject, subjectName string, relation RelationKind, object, objectName string) TableRelation {
 	return TableRelation{
 		Subject:     subject,
 		Relation:    relation,
@@ -134,11 +154,13 @@ type Api2GoModel struct {
 	columnMap         map[string]ColumnInfo
 	defaultPermission int64
 	DeleteIncludes    map[string][]string
+	AddIncludes       map[string][]string
 	relations         []TableRelation
 	data              map[string]interface{}
 	oldData           map[string]interface{}
 	Includes          []jsonapi.MarshalIdentifier
 	dirty             bool
+	clientID          bool
 }
 
 type DeleteReferenceInfo struct {
@@ -251,7 +273,10 @@ type ColumnInfo struct {
 	ForeignKeyData    ForeignKeyData `db:"foreign_key_data"`
 	DataType          string         `db:"data_type"`
 	DefaultValue      string         `db:"default_value"`
-	Options           []ValueOptions
+	// APIName is the name of the attribute in documents, sort and filter
+	// parameters. It defaults to ColumnName converted by the naming strategy.
+	APIName string `db:"api_name"`
+	Options []ValueOptions
 }
 
 type ForeignKeyData struct {
@@ -434,9 +459,8 @@ func (m *Api2GoModel) SetToManyReferenceIDs(name string, IDs []map[string]interf
 					}
 					rows = append(rows, id)
 				}
-				if len(rows) > 0 {
-					m.data[name] = rows
-				}
+				// an empty list clears the relation
+				m.data[name] = rows
 				return nil
 			} else if rel.GetRelation() == "has_one" {
 
@@ -519,8 +543,19 @@ type EditToManyRelations interface {
 
 func (m *Api2GoModel) AddToManyIDs(name string, IDs []string) error {
 
+	for _, relation := range m.relations {
+		if (relation.GetSubject() == m.typeName && relation.GetObjectName() == name) ||
+			(relation.GetObject() == m.typeName && relation.GetSubjectName() == name) {
+			if m.AddIncludes == nil {
+				m.AddIncludes = make(map[string][]string)
+			}
+			m.AddIncludes[name] = append(m.AddIncludes[name], IDs...)
+			return nil
+		}
+	}
+
 	new1 := errors.New("There is no to-manyrelationship with the name " + name)
-	log.Errorf("ERROR: ", new1)
+	log.Errorf("ERROR: %v", new1)
 	return new1
 }
 
@@ -717,22 +752,22 @@ func (model Api2GoModel) GetReferences() []jsonapi.Reference {
 		ref := jsonapi.Reference{}
 
 		if relation.GetSubject() == model.typeName {
-			switch relation.GetRelation() {
+			switch relation.Relation {
 
-			case "has_many":
+			case RelationHasMany:
 				ref.Type = relation.GetObject()
 				ref.Name = relation.GetObjectName()
 				ref.Relationship = jsonapi.ToManyRelationship
-			case "has_one":
+			case RelationHasOne:
 				ref.Type = relation.GetObject()
 				ref.Name = relation.GetObjectName()
 				ref.Relationship = jsonapi.ToOneRelationship
 
-			case "belongs_to":
+			case RelationBelongsTo:
 				ref.Type = relation.GetObject()
 				ref.Name = relation.GetObjectName()
 				ref.Relationship = jsonapi.ToOneRelationship
-			case "has_many_and_belongs_to_many":
+			case RelationManyToMany:
 				ref.Type = relation.GetObject()
 				ref.Name = relation.GetObjectName()
 				ref.Relationship = jsonapi.ToManyRelationship
@@ -741,22 +776,22 @@ func (model Api2GoModel) GetReferences() []jsonapi.Reference {
 			}
 
 		} else {
-			switch relation.GetRelation() {
+			switch relation.Relation {
 
-			case "has_many":
+			case RelationHasMany:
 				ref.Type = relation.GetSubject()
 				ref.Name = relation.GetSubjectName()
 				ref.Relationship = jsonapi.ToManyRelationship
-			case "has_one":
+			case RelationHasOne:
 				ref.Type = relation.GetSubject()
 				ref.Name = relation.GetSubjectName()
 				ref.Relationship = jsonapi.ToOneRelationship
 
-			case "belongs_to":
+			case RelationBelongsTo:
 				ref.Type = relation.GetSubject()
 				ref.Name = relation.GetSubjectName()
 				ref.Relationship = jsonapi.ToManyRelationship
-			case "has_many_and_belongs_to_many":
+			case RelationManyToMany:
 				ref.Type = relation.GetSubject()
 				ref.Name = relation.GetSubjectName()
 				ref.Relationship = jsonapi.ToManyRelationship
@@ -834,6 +869,34 @@ func (m Api2GoModel) GetColumnNames() []string {
 	return colNames
 }
 
+// GetAttributeNames returns the names of all columns exposed as attributes
+func (m Api2GoModel) GetAttributeNames() []string {
+	names := make([]string, 0, len(m.columns))
+	for _, col := range m.columns {
+		if !col.ExcludeFromApi {
+			names = append(names, col.ColumnName)
+		}
+	}
+	return names
+}
+
+// GetInternalNames returns the attributes which only serve the model, the
+// type name and the copy of the ID
+func (m Api2GoModel) GetInternalNames() []string {
+	return []string{"__type", "reference_id"}
+}
+
+// GetMemberNames maps column names to the APINames of the columns
+func (m Api2GoModel) GetMemberNames() map[string]string {
+	names := make(map[string]string)
+	for _, col := range m.columns {
+		if col.APIName != "" {
+			names[col.ColumnName] = col.APIName
+		}
+	}
+	return names
+}
+
 func (g Api2GoModel) GetDefaultPermission() int64 {
 	//log.Infof("default permission for %v is %v", g.typeName, g.defaultPermission)
 	return g.defaultPermission
@@ -1027,7 +1090,15 @@ func (g Api2GoModel) GetReferenceId() string {
 	return fmt.Sprintf("%v", g.data["reference_id"])
 }
 
+// AcceptClientID makes BeforeCreate keep the reference id sent by the client
+func (g *Api2GoModel) AcceptClientID() {
+	g.clientID = true
+}
+
 func (g *Api2GoModel) BeforeCreate() (err error) {
+	if g.clientID && g.data["reference_id"] != nil && g.data["reference_id"] != "" {
+		return nil
+	}
 	u, _ := uuid.NewV7()
 	g.data["reference_id"] = u
 	return nil
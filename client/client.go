@@ -0,0 +1,334 @@
+// Package client talks to api2go servers from Go.
+//
+// Requests and responses are jsonapi.Documents, primary data can be decoded
+// into structs implementing jsonapi.UnmarshalIdentifier with jsonapi.Unmarshal.
+//
+//	c := client.New("http://localhost:31415/v1")
+//	var user User
+//	_, err := c.Get(ctx, "users", "1", &user, nil)
+//
+//	it := c.List("users", url.Values{"page[size]": {"50"}})
+//	for it.Next(ctx) {
+//		var page []User
+//		err := it.Decode(&page)
+//	}
+//	err := it.Err()
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+const contentType = "application/vnd.api+json"
+
+// Client sends requests to the resources below BaseURL
+type Client struct {
+	// BaseURL includes the prefix of the API, e.g. http://localhost/v1
+	BaseURL    string
+	HTTPClient *http.Client
+
+	// Header is sent with every request
+	Header http.Header
+}
+
+// New returns a client using http.DefaultClient
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTPClient: http.DefaultClient, Header: make(http.Header)}
+}
+
+// ResponseError is returned for answers with a status of 400 or above.
+// Errors holds the error objects of the error document, if there was one.
+type ResponseError struct {
+	Status int
+	Errors []api2go.Error
+}
+
+func (e *ResponseError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("api2go client: status %d", e.Status)
+	}
+	first := e.Errors[0]
+	message := first.Title
+	if first.Detail != "" {
+		message += ": " + first.Detail
+	}
+	if first.Code != "" {
+		message = first.Code + " " + message
+	}
+	if len(e.Errors) > 1 {
+		message += fmt.Sprintf(" and %d more errors", len(e.Errors)-1)
+	}
+	return fmt.Sprintf("api2go client: status %d: %s", e.Status, message)
+}
+
+func (c *Client) resolve(path string) (string, error) {
+	base, err := url.Parse(c.BaseURL + "/")
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(path)
+	if err != nil {
+		return "", err
+	}
+	if ref.IsAbs() || strings.HasPrefix(path, "/") {
+		return base.ResolveReference(ref).String(), nil
+	}
+	return c.BaseURL + "/" + path, nil
+}
+
+// Do sends body to path and decodes the answer. Paths without a leading slash
+// are relative to BaseURL, absolute paths and URLs are used as they are.
+// A nil document is returned for empty answers, e.g. 204 No Content.
+func (c *Client) Do(ctx context.Context, method, path string, body interface{}) (*jsonapi.Document, error) {
+	target, err := c.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("Accept", contentType)
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	payload, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		responseError := &ResponseError{Status: res.StatusCode}
+		var errorDocument struct {
+			Errors []api2go.Error `json:"errors"`
+		}
+		if json.Unmarshal(payload, &errorDocument) == nil {
+			responseError.Errors = errorDocument.Errors
+		}
+		return nil, responseError
+	}
+
+	if len(bytes.TrimSpace(payload)) == 0 {
+		return nil, nil
+	}
+	document := &jsonapi.Document{}
+	if err := json.Unmarshal(payload, document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// Decode unmarshals the primary data of document into target with jsonapi.Unmarshal
+func Decode(document *jsonapi.Document, target interface{}) error {
+	if document == nil {
+		return errors.New("api2go client: document is empty")
+	}
+	payload, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	return jsonapi.Unmarshal(payload, target)
+}
+
+// DecodeData unmarshals a single resource object, e.g. an included one, into target
+func DecodeData(data jsonapi.Data, target interface{}) error {
+	return Decode(&jsonapi.Document{Data: &jsonapi.DataContainer{DataObject: &data}}, target)
+}
+
+func withQuery(path string, query url.Values) string {
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
+}
+
+// Get fetches a single resource and decodes it into target unless target is nil
+func (c *Client) Get(ctx context.Context, resourceType, id string, target interface{}, query url.Values) (*jsonapi.Document, error) {
+	document, err := c.Do(ctx, http.MethodGet, withQuery(resourceType+"/"+url.PathEscape(id), query), nil)
+	if err != nil || target == nil {
+		return document, err
+	}
+	return document, Decode(document, target)
+}
+
+// GetRelated fetches the resources linked by a relationship of a resource
+func (c *Client) GetRelated(ctx context.Context, resourceType, id, relationship string, target interface{}, query url.Values) (*jsonapi.Document, error) {
+	document, err := c.Do(ctx, http.MethodGet, withQuery(resourceType+"/"+url.PathEscape(id)+"/"+relationship, query), nil)
+	if err != nil || target == nil {
+		return document, err
+	}
+	return document, Decode(document, target)
+}
+
+// marshal returns the document of obj and its type
+func marshal(obj interface{}) (map[string]interface{}, *jsonapi.Data, error) {
+	payload, err := jsonapi.Marshal(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	document := &jsonapi.Document{}
+	if err := json.Unmarshal(payload, document); err != nil {
+		return nil, nil, err
+	}
+	if document.Data == nil || document.Data.DataObject == nil {
+		return nil, nil, errors.New("api2go client: expected a single resource")
+	}
+	body := make(map[string]interface{})
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, nil, err
+	}
+	return body, document.Data.DataObject, nil
+}
+
+// Create posts obj and decodes the answer back into obj, so ids and
+// attributes set by the server are available afterwards
+func (c *Client) Create(ctx context.Context, obj jsonapi.MarshalIdentifier) (*jsonapi.Document, error) {
+	body, data, err := marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	if data.ID == "" {
+		delete(body["data"].(map[string]interface{}), "id")
+	}
+	document, err := c.Do(ctx, http.MethodPost, data.Type, body)
+	if err != nil || document == nil {
+		return document, err
+	}
+	return document, Decode(document, obj)
+}
+
+// Update patches obj and decodes the answer back into obj
+func (c *Client) Update(ctx context.Context, obj jsonapi.MarshalIdentifier) (*jsonapi.Document, error) {
+	body, data, err := marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	document, err := c.Do(ctx, http.MethodPatch, data.Type+"/"+url.PathEscape(data.ID), body)
+	if err != nil || document == nil {
+		return document, err
+	}
+	return document, Decode(document, obj)
+}
+
+// Delete deletes a resource
+func (c *Client) Delete(ctx context.Context, resourceType, id string) error {
+	_, err := c.Do(ctx, http.MethodDelete, resourceType+"/"+url.PathEscape(id), nil)
+	return err
+}
+
+func relationshipPath(resourceType, id, relationship string) string {
+	return resourceType + "/" + url.PathEscape(id) + "/relationships/" + relationship
+}
+
+// Relationship fetches the linkage of a relationship
+func (c *Client) Relationship(ctx context.Context, resourceType, id, relationship string) (*jsonapi.Document, error) {
+	return c.Do(ctx, http.MethodGet, relationshipPath(resourceType, id, relationship), nil)
+}
+
+// SetToOne replaces a to-one relationship, an empty relatedID clears it
+func (c *Client) SetToOne(ctx context.Context, resourceType, id, relationship, relatedType, relatedID string) error {
+	var data interface{}
+	if relatedID != "" {
+		data = jsonapi.RelationshipData{Type: relatedType, ID: relatedID}
+	}
+	_, err := c.Do(ctx, http.MethodPatch, relationshipPath(resourceType, id, relationship), map[string]interface{}{"data": data})
+	return err
+}
+
+func linkage(relatedType string, relatedIDs []string) map[string]interface{} {
+	data := make([]jsonapi.RelationshipData, 0, len(relatedIDs))
+	for _, relatedID := range relatedIDs {
+		data = append(data, jsonapi.RelationshipData{Type: relatedType, ID: relatedID})
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// ReplaceToMany replaces all members of a to-many relationship
+func (c *Client) ReplaceToMany(ctx context.Context, resourceType, id, relationship, relatedType string, relatedIDs ...string) error {
+	_, err := c.Do(ctx, http.MethodPatch, relationshipPath(resourceType, id, relationship), linkage(relatedType, relatedIDs))
+	return err
+}
+
+// AddToMany adds members to a to-many relationship
+func (c *Client) AddToMany(ctx context.Context, resourceType, id, relationship, relatedType string, relatedIDs ...string) error {
+	_, err := c.Do(ctx, http.MethodPost, relationshipPath(resourceType, id, relationship), linkage(relatedType, relatedIDs))
+	return err
+}
+
+// DeleteFromMany removes members from a to-many relationship
+func (c *Client) DeleteFromMany(ctx context.Context, resourceType, id, relationship, relatedType string, relatedIDs ...string) error {
+	_, err := c.Do(ctx, http.MethodDelete, relationshipPath(resourceType, id, relationship), linkage(relatedType, relatedIDs))
+	return err
+}
+
+// Related returns the resources of document, either primary or included,
+// which are referenced by the relationship of data. References to resources
+// which are not part of the document are skipped.
+func Related(document *jsonapi.Document, data jsonapi.Data, relationship string) []jsonapi.Data {
+	linkage, ok := data.Relationships[relationship]
+	if !ok || linkage.Data == nil || document == nil {
+		return nil
+	}
+
+	references := linkage.Data.DataArray
+	if linkage.Data.DataObject != nil {
+		references = []jsonapi.RelationshipData{*linkage.Data.DataObject}
+	}
+
+	candidates := make([]jsonapi.Data, 0, len(document.Included))
+	candidates = append(candidates, document.Included...)
+	if document.Data != nil {
+		if document.Data.DataObject != nil {
+			candidates = append(candidates, *document.Data.DataObject)
+		}
+		candidates = append(candidates, document.Data.DataArray...)
+	}
+
+	result := make([]jsonapi.Data, 0, len(references))
+	for _, reference := range references {
+		for _, candidate := range candidates {
+			if candidate.Type == reference.Type && candidate.ID == reference.ID {
+				result = append(result, candidate)
+				break
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// Iterator walks through the pages of a collection by following the next
+// link of every page, either "next" or the "next_page_url" written by api2go
+type Iterator struct {
+	client   *Client
+	next     string
+	document *jsonapi.Document
+	err      error
+}
+
+// List returns an iterator over the pages of a collection, the first page is
+// requested with the first call of Next
+func (c *Client) List(resourceType string, query url.Values) *Iterator {
+	return &Iterator{client: c, next: withQuery(resourceType, query)}
+}
+
+// Next fetches the next page, it returns false when there are no more pages
+// or an error occurred
+func (it *Iterator) Next(ctx context.Context) bool {
+	if it.err != nil || it.next == "" {
+		return false
+	}
+
+	document, err := it.client.Do(ctx, http.MethodGet, it.next, nil)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	if document == nil {
+		it.next = ""
+		return false
+	}
+
+	previous := it.next
+	it.document = document
+	it.next = nextLink(document)
+	if it.next == previous {
+		it.next = ""
+	}
+	return true
+}
+
+// Document returns the current page
+func (it *Iterator) Document() *jsonapi.Document {
+	return it.document
+}
+
+// Decode unmarshals the resources of the current page into target, which
+// must be a pointer to a slice
+func (it *Iterator) Decode(target interface{}) error {
+	return Decode(it.document, target)
+}
+
+// Err returns the error which stopped the iteration
+func (it *Iterator) Err() error {
+	return it.err
+}
+
+func nextLink(document *jsonapi.Document) string {
+	for _, name := range []string{"next", "next_page_url"} {
+		switch link := document.Links[name].(type) {
+		case string:
+			if link != "" {
+				return link
+			}
+		case map[string]interface{}:
+			if href, ok := link["href"].(string); ok && href != "" {
+				return href
+			}
+		}
+	}
+	return ""
+}
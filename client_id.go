@@ -0,0 +1,112 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ClientIDMode decides whether clients may send the id of a resource they create
+type ClientIDMode int
+
+const (
+	// ClientIDForbidden answers creates with an id with 403 Forbidden
+	ClientIDForbidden ClientIDMode = iota
+	// ClientIDAllowed accepts creates with and without id
+	ClientIDAllowed
+	// ClientIDRequired answers creates without id with 400 Bad Request
+	ClientIDRequired
+)
+
+// ClientIDPolicy describes how a resource handles client generated ids.
+// Validate checks the format of an id, nil accepts any id.
+type ClientIDPolicy struct {
+	Mode     ClientIDMode
+	Validate func(id string) error
+}
+
+// The ClientIDAcceptor interface can be implemented by resource structs which
+// generate their own id on create, e.g. in a BeforeCreate hook. AcceptClientID
+// is called after unmarshalling if the policy of the resource accepted an id
+// sent by the client, which must then be kept.
+type ClientIDAcceptor interface {
+	AcceptClientID()
+}
+
+// ValidateUUID accepts ids in the canonical UUID format
+func ValidateUUID(id string) error {
+	if len(id) != 36 {
+		return fmt.Errorf("%s is not a valid UUID", id)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%s is not a valid UUID", id)
+	}
+	return nil
+}
+
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ValidateULID accepts ids in the 26 character ULID format
+func ValidateULID(id string) error {
+	if len(id) != 26 || id[0] > '7' {
+		return fmt.Errorf("%s is not a valid ULID", id)
+	}
+	for _, c := range strings.ToUpper(id) {
+		if !strings.ContainsRune(crockfordAlphabet, c) {
+			return fmt.Errorf("%s is not a valid ULID", id)
+		}
+	}
+	return nil
+}
+
+// SetClientIDPolicy sets the client id policy of the resource with the given
+// name. Resources without a policy pass a client id along unchecked.
+func (api *API) SetClientIDPolicy(resourceName string, policy ClientIDPolicy) {
+	if api.clientIDPolicies == nil {
+		api.clientIDPolicies = make(map[string]ClientIDPolicy)
+	}
+	api.clientIDPolicies[resourceName] = policy
+}
+
+func newClientIDError(status int, code, title, pointer string) HTTPError {
+	httpError := NewHTTPError(errors.New(title), title, status)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(status),
+		Code:   code,
+		Title:  title,
+		Source: &ErrorSource{Pointer: pointer},
+	})
+	return httpError
+}
+
+// checkClientID applies the client id policy of the resource to the id of
+// the document. It returns whether an id was accepted.
+func (res *resource) checkClientID(id string) (bool, error) {
+	policy, ok := res.api.clientIDPolicies[res.name]
+	if !ok {
+		return false, nil
+	}
+
+	if id == "" {
+		if policy.Mode == ClientIDRequired {
+			return false, newClientIDError(http.StatusBadRequest, codeClientIDRequired,
+				fmt.Sprintf("Resource %s requires a client generated id", res.name), "/data")
+		}
+		return false, nil
+	}
+
+	if policy.Mode == ClientIDForbidden {
+		return false, newClientIDError(http.StatusForbidden, codeClientIDForbidden,
+			fmt.Sprintf("Resource %s does not support client generated ids", res.name), "/data/id")
+	}
+	if policy.Validate != nil {
+		if err := policy.Validate(id); err != nil {
+			return false, newClientIDError(http.StatusBadRequest, codeInvalidClientID, err.Error(), "/data/id")
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,105 @@
+package api2go
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures Cross-Origin Resource Sharing for all generated routes
+type CORSConfig struct {
+	// AllowedOrigins are exact origins like "https://app.example.com", origins
+	// with one wildcard like "https://*.example.com" or "*" for any origin
+	AllowedOrigins []string
+	// AllowOriginFunc is asked for origins which are not in AllowedOrigins
+	AllowOriginFunc func(origin string) bool
+	// AllowCredentials allows cookies and authorization headers
+	AllowCredentials bool
+	// AllowedHeaders are the request headers allowed in preflights. If empty,
+	// the headers requested by the browser are allowed.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers readable by the browser, e.g.
+	// "Location" and "ETag"
+	ExposedHeaders []string
+	// MaxAge is how long browsers may cache a preflight result
+	MaxAge time.Duration
+}
+
+// SetCORS enables CORS headers on all generated routes, including preflight
+// answers on OPTIONS and error documents
+func (api *API) SetCORS(config CORSConfig) {
+	api.cors = &config
+}
+
+func (c *CORSConfig) allowOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(origin)
+}
+
+func (c *CORSConfig) anyOrigin() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCORSHeaders adds the Access-Control-* headers for the origin of r.
+// allowedMethods is only needed for preflights.
+func (api *API) writeCORSHeaders(w http.ResponseWriter, r *http.Request, allowedMethods []string) {
+	config := api.cors
+	if config == nil {
+		return
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return
+	}
+
+	header := w.Header()
+	header.Add("Vary", "Origin")
+	if !config.allowOrigin(origin) {
+		return
+	}
+
+	if config.anyOrigin() && !config.AllowCredentials {
+		header.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		header.Set("Access-Control-Allow-Origin", origin)
+	}
+	if config.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		if len(config.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+		}
+		return
+	}
+
+	// preflight
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(allowedMethods, ", "))
+	if len(config.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if config.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+	}
+}
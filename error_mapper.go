@@ -0,0 +1,114 @@
+package api2go
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// Logger is used for errors and diagnostics, *log.Logger and most structured
+// loggers implement it
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// An ErrorMapper translates errors returned by data sources into HTTPErrors.
+// ok is false if the mapper does not handle err.
+type ErrorMapper func(err error) (httpError HTTPError, ok bool)
+
+// MapError returns an ErrorMapper for errors matching target with errors.Is,
+// e.g. MapError(sql.ErrNoRows, http.StatusNotFound, "Not Found")
+func MapError(target error, status int, title string) ErrorMapper {
+	return func(err error) (HTTPError, bool) {
+		if !errors.Is(err, target) {
+			return HTTPError{}, false
+		}
+		return NewHTTPError(err, title, status), true
+	}
+}
+
+// MapErrorAs returns an ErrorMapper for errors of type T, found with errors.As
+func MapErrorAs[T error](mapper func(T) HTTPError) ErrorMapper {
+	return func(err error) (HTTPError, bool) {
+		var target T
+		if !errors.As(err, &target) {
+			return HTTPError{}, false
+		}
+		return mapper(target), true
+	}
+}
+
+// RegisterErrorMapper adds a mapper for errors which are not HTTPErrors.
+// Mappers are tried in the order they were registered.
+func (api *API) RegisterErrorMapper(mapper ErrorMapper) {
+	api.errorHandling.mappers = append(api.errorHandling.mappers, mapper)
+}
+
+// SetProductionMode hides the messages of errors which are neither HTTPErrors
+// nor mapped, and adds a correlation id to every error object, which is also
+// logged together with the error. The id is the request id if there is one.
+func (api *API) SetProductionMode(enabled bool) {
+	api.errorHandling.production = enabled
+}
+
+// SetLogger replaces the standard logger
+func (api *API) SetLogger(logger Logger) {
+	api.errorHandling.logger = logger
+}
+
+// errorHandling turns errors into error documents
+type errorHandling struct {
+	mappers    []ErrorMapper
+	production bool
+	logger     Logger
+}
+
+func (e errorHandling) logf(format string, v ...interface{}) {
+	if e.logger != nil {
+		e.logger.Printf(format, v...)
+		return
+	}
+	log.Printf(format, v...)
+}
+
+// toHTTPError returns err as HTTPError, mapped if necessary
+func (e errorHandling) toHTTPError(err error) (HTTPError, bool) {
+	if httpError, ok := err.(HTTPError); ok {
+		return httpError, true
+	}
+	for _, mapper := range e.mappers {
+		if httpError, ok := mapper(err); ok {
+			return httpError, true
+		}
+	}
+	if e.production {
+		return NewHTTPError(err, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError), false
+	}
+	return NewHTTPError(err, err.Error(), http.StatusInternalServerError), false
+}
+
+func (e errorHandling) handle(err error, w http.ResponseWriter, r *http.Request, contentType string) {
+	httpError, _ := e.toHTTPError(err)
+
+	if !e.production {
+		e.logf("%v", err)
+		writeResult(w, []byte(marshalHTTPError(httpError)), httpError.status, contentType)
+		return
+	}
+
+	id := requestID(r)
+	e.logf("%s %v", id, err)
+	if len(httpError.Errors) == 0 {
+		httpError.Errors = []Error{{Title: httpError.msg, Status: strconv.Itoa(httpError.status)}}
+	}
+	errs := make([]Error, len(httpError.Errors))
+	for i, e := range httpError.Errors {
+		if e.ID == "" {
+			e.ID = id
+		}
+		errs[i] = e
+	}
+	httpError.Errors = errs
+	writeResult(w, []byte(marshalHTTPError(httpError)), httpError.status, contentType)
+}
@@ -0,0 +1,108 @@
+package api2go_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testClient sends JSON:API requests to the handler of an api
+type testClient struct {
+	t       *testing.T
+	handler http.Handler
+}
+
+type testRecord struct {
+	ID         string                 `json:"id"`
+	Attributes map[string]interface{} `json:"attributes"`
+}
+
+type testResponse struct {
+	t      *testing.T
+	Status int
+	Data   json.RawMessage        `json:"data"`
+	Links  map[string]interface{} `json:"links"`
+}
+
+func (c testClient) do(method, path string, body interface{}) *testResponse {
+	c.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	r := httptest.NewRequest(method, "/v1/"+path, reader)
+	r.Header.Set("Content-Type", "application/vnd.api+json")
+	w := httptest.NewRecorder()
+	c.handler.ServeHTTP(w, r)
+
+	response := &testResponse{t: c.t, Status: w.Code}
+	if w.Body.Len() > 0 && strings.Contains(w.Header().Get("Content-Type"), "json") {
+		if err := json.Unmarshal(w.Body.Bytes(), response); err != nil {
+			c.t.Fatalf("%s %s: %v in %s", method, path, err, w.Body.String())
+		}
+	}
+	return response
+}
+
+// resource returns a document with one resource of type typ
+func resource(typ, id string, attributes map[string]interface{}, relationships map[string]interface{}) map[string]interface{} {
+	data := map[string]interface{}{"type": typ, "attributes": attributes}
+	if id != "" {
+		data["id"] = id
+	}
+	if relationships != nil {
+		data["relationships"] = relationships
+	}
+	return map[string]interface{}{"data": data}
+}
+
+// identifiers returns the resource identifier objects of ids
+func identifiers(typ string, ids ...string) map[string]interface{} {
+	data := make([]map[string]interface{}, len(ids))
+	for i, id := range ids {
+		data[i] = map[string]interface{}{"type": typ, "id": id}
+	}
+	return map[string]interface{}{"data": data}
+}
+
+func (r *testResponse) expect(status int) *testResponse {
+	r.t.Helper()
+	if r.Status != status {
+		r.t.Fatalf("expected status %d, got %d", status, r.Status)
+	}
+	return r
+}
+
+func (r *testResponse) record() testRecord {
+	r.t.Helper()
+	var record testRecord
+	if err := json.Unmarshal(r.Data, &record); err != nil {
+		r.t.Fatal(err)
+	}
+	return record
+}
+
+func (r *testResponse) records() []testRecord {
+	r.t.Helper()
+	var records []testRecord
+	if err := json.Unmarshal(r.Data, &records); err != nil {
+		r.t.Fatal(err)
+	}
+	return records
+}
+
+// attribute returns an attribute of the single resource in r as text
+func (r *testResponse) attribute(name string) string {
+	r.t.Helper()
+	return fmt.Sprint(r.record().Attributes[name])
+}
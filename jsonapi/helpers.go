@@ -3,8 +3,6 @@ package jsonapi
 import (
 	"strings"
 	"unicode"
-
-	//"github.com/gedex/inflector"
 )
 
 // https://github.com/golang/lint/blob/3d26dc39376c307203d3a221bada26816b3073cf/lint.go#L482
@@ -58,9 +56,3 @@ func Jsonify(s string) string {
 
 	return string(rs)
 }
-
-// Pluralize returns the pluralization of a noun.
-func Pluralize(word string) string {
-	return word
-	//return inflector.Pluralize(word)
-}
@@ -0,0 +1,238 @@
+package jsonapi
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// inflectionRule replaces the end of a word matched by pattern
+type inflectionRule struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// inflections holds the rules of the English inflector. Rules are checked in
+// order, so the rules added by the user come first.
+type inflections struct {
+	mutex       sync.RWMutex
+	plurals     []inflectionRule
+	singulars   []inflectionRule
+	irregulars  map[string]string // singular to plural
+	singularOf  map[string]string // plural to singular
+	uncountable map[string]bool
+}
+
+var inflector = newInflections()
+
+func newInflections() *inflections {
+	i := &inflections{
+		irregulars:  map[string]string{},
+		singularOf:  map[string]string{},
+		uncountable: map[string]bool{},
+	}
+
+	// the rules of Rails' ActiveSupport, most specific first
+	for _, rule := range [][2]string{
+		{`(quiz)$`, "${1}zes"},
+		{`^(oxen)$`, "${1}"},
+		{`^(ox)$`, "${1}en"},
+		{`^(m|l)ice$`, "${1}ice"},
+		{`^(m|l)ouse$`, "${1}ice"},
+		{`(matr|vert|ind)(?:ix|ex)$`, "${1}ices"},
+		{`(x|ch|ss|sh)$`, "${1}es"},
+		{`([^aeiouy]|qu)y$`, "${1}ies"},
+		{`(hive)$`, "${1}s"},
+		{`(?:([^f])fe|([lr])f)$`, "${1}${2}ves"},
+		{`sis$`, "ses"},
+		{`([ti])a$`, "${1}a"},
+		{`([ti])um$`, "${1}a"},
+		{`(buffal|tomat)o$`, "${1}oes"},
+		{`(bu)s$`, "${1}ses"},
+		{`(alias|status)$`, "${1}es"},
+		{`(octop|vir)i$`, "${1}i"},
+		{`(octop|vir)us$`, "${1}i"},
+		{`^(ax|test)is$`, "${1}es"},
+		{`s$`, "s"},
+		{`$`, "s"},
+	} {
+		i.plurals = append(i.plurals, newInflectionRule(rule[0], rule[1]))
+	}
+
+	for _, rule := range [][2]string{
+		{`(database)s$`, "${1}"},
+		{`(quiz)zes$`, "${1}"},
+		{`(matr)ices$`, "${1}ix"},
+		{`(vert|ind)ices$`, "${1}ex"},
+		{`^(ox)en`, "${1}"},
+		{`(alias|status)(es)?$`, "${1}"},
+		{`(octop|vir)(us|i)$`, "${1}us"},
+		{`^(a)x[ie]s$`, "${1}xis"},
+		{`(cris|test)(is|es)$`, "${1}is"},
+		{`(shoe)s$`, "${1}"},
+		{`(o)es$`, "${1}"},
+		{`(bus)(es)?$`, "${1}"},
+		{`^(m|l)ice$`, "${1}ouse"},
+		{`(x|ch|ss|sh)es$`, "${1}"},
+		{`(m)ovies$`, "${1}ovie"},
+		{`(s)eries$`, "${1}eries"},
+		{`([^aeiouy]|qu)ies$`, "${1}y"},
+		{`([lr])ves$`, "${1}f"},
+		{`(tive)s$`, "${1}"},
+		{`(hive)s$`, "${1}"},
+		{`([^f])ves$`, "${1}fe"},
+		{`(^analy)(sis|ses)$`, "${1}sis"},
+		{`((a)naly|(b)a|(d)iagno|(p)arenthe|(p)rogno|(s)ynop|(t)he)(sis|ses)$`, "${1}sis"},
+		{`([ti])a$`, "${1}um"},
+		{`(n)ews$`, "${1}ews"},
+		{`(ss)$`, "${1}"},
+		{`s$`, ""},
+	} {
+		i.singulars = append(i.singulars, newInflectionRule(rule[0], rule[1]))
+	}
+
+	for singular, plural := range map[string]string{
+		"person": "people",
+		"man":    "men",
+		"woman":  "women",
+		"child":  "children",
+		"sex":    "sexes",
+		"move":   "moves",
+		"zombie": "zombies",
+		"foot":   "feet",
+		"tooth":  "teeth",
+		"goose":  "geese",
+	} {
+		i.irregulars[singular] = plural
+		i.singularOf[plural] = singular
+	}
+
+	for _, word := range []string{
+		"equipment", "information", "rice", "money", "species", "series",
+		"fish", "sheep", "jeans", "police", "metadata", "data",
+	} {
+		i.uncountable[word] = true
+	}
+
+	return i
+}
+
+func newInflectionRule(pattern, replacement string) inflectionRule {
+	return inflectionRule{pattern: regexp.MustCompile("(?i)" + pattern), replacement: replacement}
+}
+
+// Pluralize returns the plural of an English noun. Only the last word of
+// names like "blog_post" or "BlogPost" is inflected. Plurals are returned
+// unchanged.
+func Pluralize(word string) string {
+	return inflector.inflect(word, true)
+}
+
+// Singularize returns the singular of an English noun, the counterpart of
+// Pluralize
+func Singularize(word string) string {
+	return inflector.inflect(word, false)
+}
+
+// AddIrregular registers a noun whose plural does not follow the rules, like
+// "person" and "people"
+func AddIrregular(singular, plural string) {
+	inflector.mutex.Lock()
+	defer inflector.mutex.Unlock()
+	singular, plural = strings.ToLower(singular), strings.ToLower(plural)
+	delete(inflector.uncountable, singular)
+	inflector.irregulars[singular] = plural
+	inflector.singularOf[plural] = singular
+}
+
+// AddUncountable registers nouns which have no plural, like "equipment"
+func AddUncountable(words ...string) {
+	inflector.mutex.Lock()
+	defer inflector.mutex.Unlock()
+	for _, word := range words {
+		inflector.uncountable[strings.ToLower(word)] = true
+	}
+}
+
+// AddPluralRule adds a rule which Pluralize checks before all others. The
+// pattern is a case insensitive regular expression, the replacement can
+// refer to its groups, e.g. AddPluralRule(`(vert)ex$`, "${1}ices").
+func AddPluralRule(pattern, replacement string) {
+	rule := newInflectionRule(pattern, replacement)
+	inflector.mutex.Lock()
+	defer inflector.mutex.Unlock()
+	inflector.plurals = append([]inflectionRule{rule}, inflector.plurals...)
+}
+
+// AddSingularRule adds a rule which Singularize checks before all others, see
+// AddPluralRule
+func AddSingularRule(pattern, replacement string) {
+	rule := newInflectionRule(pattern, replacement)
+	inflector.mutex.Lock()
+	defer inflector.mutex.Unlock()
+	inflector.singulars = append([]inflectionRule{rule}, inflector.singulars...)
+}
+
+func (i *inflections) inflect(word string, plural bool) string {
+	if word == "" {
+		return word
+	}
+
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	prefix, last := splitLastWord(word)
+	lower := strings.ToLower(last)
+	if i.uncountable[lower] {
+		return word
+	}
+
+	irregulars, others := i.singularOf, i.irregulars
+	rules := i.singulars
+	if plural {
+		irregulars, others = i.irregulars, i.singularOf
+		rules = i.plurals
+	}
+	if replacement, ok := irregulars[lower]; ok {
+		return prefix + matchCase(last, replacement)
+	}
+	if _, ok := others[lower]; ok {
+		// already in the wanted form
+		return word
+	}
+
+	for _, rule := range rules {
+		if rule.pattern.MatchString(last) {
+			return prefix + rule.pattern.ReplaceAllString(last, rule.replacement)
+		}
+	}
+	return word
+}
+
+// splitLastWord splits "blog_post", "blog-post" and "BlogPost" before "post"
+func splitLastWord(word string) (string, string) {
+	runes := []rune(word)
+	for i := len(runes) - 1; i > 0; i-- {
+		switch {
+		case runes[i-1] == '_' || runes[i-1] == '-' || runes[i-1] == ' ':
+			return string(runes[:i]), string(runes[i:])
+		case unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i-1]):
+			return string(runes[:i]), string(runes[i:])
+		}
+	}
+	return "", word
+}
+
+// matchCase capitalizes replacement like word
+func matchCase(word, replacement string) string {
+	if word == strings.ToUpper(word) && len(word) > 1 {
+		return strings.ToUpper(replacement)
+	}
+	if first := []rune(word)[0]; unicode.IsUpper(first) {
+		runes := []rune(replacement)
+		runes[0] = unicode.ToUpper(runes[0])
+		return string(runes)
+	}
+	return replacement
+}
@@ -0,0 +1,86 @@
+package jsonapi
+
+import "testing"
+
+func TestInflectionRoundTrip(t *testing.T) {
+	tests := []struct {
+		singular string
+		plural   string
+	}{
+		{"post", "posts"},
+		{"category", "categories"},
+		{"box", "boxes"},
+		{"church", "churches"},
+		{"wife", "wives"},
+		{"half", "halves"},
+		{"matrix", "matrices"},
+		{"index", "indices"},
+		{"quiz", "quizzes"},
+		{"analysis", "analyses"},
+		{"status", "statuses"},
+		{"octopus", "octopi"},
+		{"tomato", "tomatoes"},
+		{"movie", "movies"},
+		{"ox", "oxen"},
+		{"mouse", "mice"},
+		// irregulars
+		{"person", "people"},
+		{"child", "children"},
+		{"tooth", "teeth"},
+		// uncountables
+		{"equipment", "equipment"},
+		{"sheep", "sheep"},
+		{"series", "series"},
+		// the last word is inflected, in its case
+		{"blog_post", "blog_posts"},
+		{"blog-person", "blog-people"},
+		{"BlogPerson", "BlogPeople"},
+		{"Person", "People"},
+		{"PERSON", "PEOPLE"},
+	}
+
+	for _, test := range tests {
+		if got := Pluralize(test.singular); got != test.plural {
+			t.Errorf("expected the plural of %s to be %s, got %s", test.singular, test.plural, got)
+		}
+		if got := Singularize(test.plural); got != test.singular {
+			t.Errorf("expected the singular of %s to be %s, got %s", test.plural, test.singular, got)
+		}
+		if got := Pluralize(test.plural); got != test.plural {
+			t.Errorf("expected the plural %s to stay unchanged, got %s", test.plural, got)
+		}
+	}
+	if got := Pluralize(""); got != "" {
+		t.Errorf("expected an empty name to stay empty, got %q", got)
+	}
+}
+
+// the words are made up, as the inflector is shared by all tests
+func TestInflectionOverrides(t *testing.T) {
+	AddIrregular("blorp", "blorpen")
+	if got := Pluralize("blorp"); got != "blorpen" {
+		t.Errorf("expected the irregular plural blorpen, got %s", got)
+	}
+	if got := Singularize("Blorpen"); got != "Blorp" {
+		t.Errorf("expected the irregular singular Blorp, got %s", got)
+	}
+
+	AddUncountable("Glorf")
+	if got := Pluralize("glorf"); got != "glorf" {
+		t.Errorf("expected the uncountable glorf to stay unchanged, got %s", got)
+	}
+	// an irregular replaces an uncountable
+	AddIrregular("glorf", "glorves")
+	if got := Pluralize("glorf"); got != "glorves" {
+		t.Errorf("expected the irregular plural glorves, got %s", got)
+	}
+
+	AddPluralRule(`(vort)ex$`, "${1}ices")
+	AddSingularRule(`(vort)ices$`, "${1}ex")
+	if got := Pluralize("snorvortex"); got != "snorvortices" {
+		t.Errorf("expected the plural rule to come first, got %s", got)
+	}
+	if got := Singularize("snorvortices"); got != "snorvortex" {
+		t.Errorf("expected the singular rule to come first, got %s", got)
+	}
+}
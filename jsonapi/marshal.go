@@ -110,7 +110,8 @@ func MarshalWithURLs(data interface{}, information ServerInformation) ([]byte, e
 // Marshal wraps data in a Document and returns its JSON encoding.
 //
 // Data can be a struct, a pointer to a struct or a slice of structs. All structs
-// must at least implement the `MarshalIdentifier` interface.
+// must at least implement the `MarshalIdentifier` interface or have `jsonapi`
+// struct tags.
 func Marshal(data interface{}) ([]byte, error) {
 	document, err := MarshalToStruct(data, nil)
 	if err != nil {
@@ -133,7 +134,11 @@ func MarshalToStruct(data interface{}, information ServerInformation) (*Document
 	case reflect.Slice:
 		return marshalSlice(data, information)
 	case reflect.Struct, reflect.Ptr, reflect.Map:
-		return marshalStruct(data.(MarshalIdentifier), information)
+		element, err := asMarshalIdentifier(data)
+		if err != nil {
+			return nil, err
+		}
+		return marshalStruct(element, information)
 	default:
 		return nil, errors.New(fmt.Sprintf("Marshal only accepts slice, struct or ptr types, %v", reflect.TypeOf(data).Kind()))
 	}
@@ -168,18 +173,17 @@ func marshalSlice(data interface{}, information ServerInformation) (*Document, e
 	var referencedStructs []MarshalIdentifier
 
 	for i := 0; i < val.Len(); i++ {
-		k := val.Index(i).Interface()
-		element, ok := k.(MarshalIdentifier)
-		if !ok {
-			return nil, errors.New("all elements within the slice must implement api2go.MarshalIdentifier")
+		element, err := asMarshalIdentifier(val.Index(i).Interface())
+		if err != nil {
+			return nil, errors.New("all elements within the slice must implement api2go.MarshalIdentifier or have jsonapi struct tags")
 		}
 
-		err := marshalData(element, &dataElements[i], information)
+		err = marshalData(element, &dataElements[i], information)
 		if err != nil {
 			return nil, err
 		}
 
-		included, ok := k.(MarshalIncludedRelations)
+		included, ok := element.(MarshalIncludedRelations)
 		if ok {
 			referencedStructs = append(referencedStructs, included.GetReferencedStructs()...)
 		}
@@ -229,15 +233,16 @@ func filterDuplicates(input []MarshalIdentifier, information ServerInformation)
 }
 
 func marshalData(element MarshalIdentifier, data *Data, information ServerInformation) error {
+	naming := namingOf(information)
 
-	attributes, err := json.Marshal(element.GetAttributes())
+	attributes, err := json.Marshal(convertAttributes(element, naming.members(element)))
 	if err != nil {
 		return err
 	}
 
 	data.Attributes = attributes
 	data.ID = element.GetID()
-	data.Type = getStructType(element)
+	data.Type = naming.Name(getStructType(element))
 
 	if information != nil {
 		if customLinks, ok := element.(MarshalCustomLinks); ok {
@@ -282,6 +287,7 @@ func getMetaForRelation(metaSource MarshalCustomRelationshipMeta, name string, i
 }
 
 func getStructRelationships(relationer MarshalLinkedRelations, information ServerInformation) map[string]Relationship {
+	naming := namingOf(information)
 	referencedIDs := relationer.GetReferencedIDs()
 	sortedResults := map[string][]ReferenceID{}
 	relationships := map[string]Relationship{}
@@ -299,8 +305,6 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 	}
 
 	for name, referenceIDs := range sortedResults {
-		relationships[name] = Relationship{}
-
 		// if referenceType is plural, we need to use an array for data, otherwise it's just an object
 		container := RelationshipDataContainer{}
 
@@ -309,13 +313,13 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			container.DataArray = []RelationshipData{}
 			for _, referenceID := range referenceIDs {
 				container.DataArray = append(container.DataArray, RelationshipData{
-					Type: referenceID.Type,
+					Type: naming.Name(referenceID.Type),
 					ID:   referenceID.ID,
 				})
 			}
 		} else {
 			container.DataObject = &RelationshipData{
-				Type: referenceIDs[0].Type,
+				Type: naming.Name(referenceIDs[0].Type),
 				ID:   referenceIDs[0].ID,
 			}
 		}
@@ -335,7 +339,7 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			Meta:  meta,
 		}
 
-		relationships[name] = relationship
+		relationships[naming.MemberName(relationer, name)] = relationship
 
 		// this marks the reference as already included
 		delete(notIncludedReferences, referenceIDs[0].Name)
@@ -368,7 +372,7 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 			relationship.Data = &container
 		}
 
-		relationships[name] = relationship
+		relationships[naming.MemberName(relationer, name)] = relationship
 	}
 
 	return relationships
@@ -377,7 +381,7 @@ func getStructRelationships(relationer MarshalLinkedRelations, information Serve
 func getLinkBaseURL(element MarshalIdentifier, information ServerInformation) string {
 	prefix := strings.Trim(information.GetBaseURL(), "/")
 	namespace := strings.Trim(information.GetPrefix(), "/")
-	structType := getStructType(element)
+	structType := namingOf(information).Name(getStructType(element))
 
 	if namespace != "" {
 		prefix += "/" + namespace
@@ -434,6 +438,9 @@ func getStructType(data interface{}) string {
 	}
 
 	reflectType := reflect.TypeOf(data)
+	if schema, err := schemaOf(reflectType); err == nil && schema != nil {
+		return schema.typeName
+	}
 	if reflectType.Kind() == reflect.Ptr {
 		return Pluralize(Jsonify(reflectType.Elem().Name()))
 	}
@@ -0,0 +1,277 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy decides how type names, attribute keys and relationship
+// names appear in documents. Resources keep using their own names, Marshal
+// converts them and Unmarshal maps them back.
+type NamingStrategy int
+
+const (
+	// NamingAsIs uses the names of the resources unchanged
+	NamingAsIs NamingStrategy = iota
+	// NamingCamelCase writes names like "blogPost"
+	NamingCamelCase
+	// NamingKebabCase writes names like "blog-post"
+	NamingKebabCase
+	// NamingSnakeCase writes names like "blog_post"
+	NamingSnakeCase
+)
+
+// The NamingInformation interface can be implemented by a ServerInformation
+// to have Marshal convert all names with a NamingStrategy
+type NamingInformation interface {
+	GetNamingStrategy() NamingStrategy
+}
+
+// The AttributeNamer interface can be implemented to list all attribute
+// names, including those GetAttributes leaves out for empty values. Unmarshal
+// needs them to map converted names back.
+type AttributeNamer interface {
+	GetAttributeNames() []string
+}
+
+// The MemberNamer interface can be implemented to give attributes and
+// relationships other names in documents. GetMemberNames maps own names to
+// document names, the NamingStrategy converts all names not in the map.
+type MemberNamer interface {
+	GetMemberNames() map[string]string
+}
+
+// The InternalNamer interface can be implemented to name the attributes
+// GetAttributes returns for the element's own use, like a copy of the ID.
+// They are left out of documents whose names are converted, where they could
+// turn into the reserved names type and id.
+type InternalNamer interface {
+	GetInternalNames() []string
+}
+
+// reservedMembers are the names JSON:API does not allow for attributes and
+// relationships
+var reservedMembers = map[string]bool{"type": true, "id": true}
+
+// Name converts a name in camelCase, PascalCase, kebab-case or snake_case
+func (s NamingStrategy) Name(name string) string {
+	switch s {
+	case NamingCamelCase:
+		words := Words(name)
+		for i := 1; i < len(words); i++ {
+			runes := []rune(words[i])
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, "")
+	case NamingKebabCase:
+		return strings.Join(Words(name), "-")
+	case NamingSnakeCase:
+		return strings.Join(Words(name), "_")
+	}
+	return name
+}
+
+func (s NamingStrategy) String() string {
+	switch s {
+	case NamingAsIs:
+		return "as-is"
+	case NamingCamelCase:
+		return "camelCase"
+	case NamingKebabCase:
+		return "kebab-case"
+	case NamingSnakeCase:
+		return "snake_case"
+	}
+	return fmt.Sprintf("NamingStrategy(%d)", int(s))
+}
+
+// Words splits a name into lower case words. Underscores, hyphens and spaces
+// separate words, as does a change to upper case. Initialisms stay one word,
+// e.g. "UserIDs" has the words "user" and "ids".
+func Words(name string) []string {
+	var words []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' '
+	}) {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			if !unicode.IsUpper(runes[i]) {
+				continue
+			}
+			if unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) {
+				// "userID" splits before "I"
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+				continue
+			}
+			// "HTTPServer" splits before "S", but the "s" of "IDs" belongs
+			// to the initialism
+			next := i + 1
+			if next < len(runes) && unicode.IsLower(runes[next]) && !isPluralSuffix(runes, next) {
+				words = append(words, strings.ToLower(string(runes[start:i])))
+				start = i
+			}
+		}
+		words = append(words, strings.ToLower(string(runes[start:])))
+	}
+	return words
+}
+
+// isPluralSuffix reports whether the lower case "s" at i ends the word
+func isPluralSuffix(runes []rune, i int) bool {
+	return runes[i] == 's' && (i+1 == len(runes) || unicode.IsUpper(runes[i+1]))
+}
+
+// members returns the function which converts the member names of element,
+// or nil if names are used unchanged
+func (s NamingStrategy) members(element interface{}) func(string) string {
+	var names map[string]string
+	if namer, ok := element.(MemberNamer); ok {
+		names = namer.GetMemberNames()
+	}
+	if s == NamingAsIs && len(names) == 0 {
+		return nil
+	}
+	return func(name string) string {
+		if converted, ok := names[name]; ok && converted != "" {
+			return converted
+		}
+		return s.Name(name)
+	}
+}
+
+// MemberName returns the name of a member of element in documents
+func (s NamingStrategy) MemberName(element interface{}, name string) string {
+	if member := s.members(element); member != nil {
+		return member(name)
+	}
+	return name
+}
+
+// MemberNames maps the document names of the attributes and relationships
+// of element back to the names element uses
+func (s NamingStrategy) MemberNames(element interface{}) map[string]string {
+	names := map[string]string{}
+	identifier, err := asMarshalIdentifier(element)
+	if err != nil {
+		return names
+	}
+
+	member := s.members(identifier)
+	internal := map[string]bool{}
+	if member == nil {
+		member = func(name string) string { return name }
+	} else {
+		internal = internalNames(identifier)
+	}
+	add := func(name string) {
+		if !internal[name] {
+			names[member(name)] = name
+		}
+	}
+	if namer, ok := identifier.(AttributeNamer); ok {
+		for _, name := range namer.GetAttributeNames() {
+			add(name)
+		}
+	}
+	for name := range identifier.GetAttributes() {
+		add(name)
+	}
+	if referencer, ok := identifier.(MarshalReferences); ok {
+		for _, reference := range referencer.GetReferences() {
+			add(reference.Name)
+		}
+	}
+	return names
+}
+
+// CheckMemberNames returns an error for every attribute or relationship of
+// element whose name is converted to type or id
+func (s NamingStrategy) CheckMemberNames(element interface{}) error {
+	var errs []error
+	for documentName, name := range s.MemberNames(element) {
+		if reservedMembers[documentName] && documentName != name {
+			errs = append(errs, fmt.Errorf("member %s would be named %s, which is reserved", name, documentName))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// convertAttributes returns the attributes of element with the keys converted
+// by member, leaving out the internal ones
+func convertAttributes(element MarshalIdentifier, member func(string) string) map[string]interface{} {
+	attributes := element.GetAttributes()
+	if member == nil {
+		return attributes
+	}
+	internal := internalNames(element)
+	converted := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		if !internal[name] {
+			converted[member(name)] = value
+		}
+	}
+	return converted
+}
+
+func internalNames(element interface{}) map[string]bool {
+	internal := map[string]bool{}
+	if namer, ok := element.(InternalNamer); ok {
+		for _, name := range namer.GetInternalNames() {
+			internal[name] = true
+		}
+	}
+	return internal
+}
+
+// restoreNames renames the attributes and relationships of record to the
+// names target uses. Unknown names are kept.
+func (s NamingStrategy) restoreNames(record *Data, target interface{}) error {
+	if s.members(target) == nil {
+		return nil
+	}
+	names := s.MemberNames(target)
+	restore := func(name string) string {
+		if original, ok := names[name]; ok {
+			return original
+		}
+		return name
+	}
+
+	if record.Attributes != nil {
+		attributes := map[string]interface{}{}
+		if err := json.Unmarshal(record.Attributes, &attributes); err != nil {
+			return errors.New("attributes must be an object")
+		}
+		restored := make(map[string]interface{}, len(attributes))
+		for name, value := range attributes {
+			restored[restore(name)] = value
+		}
+		raw, err := json.Marshal(restored)
+		if err != nil {
+			return err
+		}
+		record.Attributes = raw
+	}
+
+	if record.Relationships != nil {
+		restored := make(map[string]Relationship, len(record.Relationships))
+		for name, relationship := range record.Relationships {
+			restored[restore(name)] = relationship
+		}
+		record.Relationships = restored
+	}
+	return nil
+}
+
+// namingOf returns the strategy of information, if it has one
+func namingOf(information ServerInformation) NamingStrategy {
+	if naming, ok := information.(NamingInformation); ok {
+		return naming.GetNamingStrategy()
+	}
+	return NamingAsIs
+}
@@ -0,0 +1,98 @@
+package jsonapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamingStrategyName(t *testing.T) {
+	tests := []struct {
+		name  string
+		camel string
+		kebab string
+		snake string
+	}{
+		{"blog_post", "blogPost", "blog-post", "blog_post"},
+		{"blog-post", "blogPost", "blog-post", "blog_post"},
+		{"BlogPost", "blogPost", "blog-post", "blog_post"},
+		{"blogPost", "blogPost", "blog-post", "blog_post"},
+		{"userID", "userId", "user-id", "user_id"},
+		{"UserIDs", "userIds", "user-ids", "user_ids"},
+		{"HTTPServer", "httpServer", "http-server", "http_server"},
+		{"post2Tag", "post2Tag", "post2-tag", "post2_tag"},
+		{"name", "name", "name", "name"},
+	}
+
+	for _, test := range tests {
+		if got := NamingCamelCase.Name(test.name); got != test.camel {
+			t.Errorf("expected camelCase of %s to be %s, got %s", test.name, test.camel, got)
+		}
+		if got := NamingKebabCase.Name(test.name); got != test.kebab {
+			t.Errorf("expected kebab-case of %s to be %s, got %s", test.name, test.kebab, got)
+		}
+		if got := NamingSnakeCase.Name(test.name); got != test.snake {
+			t.Errorf("expected snake_case of %s to be %s, got %s", test.name, test.snake, got)
+		}
+		if got := NamingAsIs.Name(test.name); got != test.name {
+			t.Errorf("expected %s to stay unchanged, got %s", test.name, got)
+		}
+	}
+}
+
+// namedPost has attributes in snake_case, one of them internal
+type namedPost struct{}
+
+func (namedPost) GetID() string { return "1" }
+
+func (namedPost) GetAttributes() map[string]interface{} {
+	return map[string]interface{}{"blog_title": "notes", "__type": "named_post"}
+}
+
+func (namedPost) GetAttributeNames() []string {
+	return []string{"blog_title", "published_at"}
+}
+
+func (namedPost) GetInternalNames() []string {
+	return []string{"__type"}
+}
+
+func (namedPost) GetReferences() []Reference {
+	return []Reference{{Type: "authors", Name: "main_author"}}
+}
+
+func TestNamingStrategyMembers(t *testing.T) {
+	got := NamingCamelCase.MemberNames(namedPost{})
+	want := map[string]string{"blogTitle": "blog_title", "publishedAt": "published_at", "mainAuthor": "main_author"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the member names %v, got %v", want, got)
+	}
+
+	attributes := convertAttributes(namedPost{}, NamingKebabCase.members(namedPost{}))
+	if !reflect.DeepEqual(attributes, map[string]interface{}{"blog-title": "notes"}) {
+		t.Errorf("expected the converted attributes without the internal one, got %v", attributes)
+	}
+	if attributes := convertAttributes(namedPost{}, NamingAsIs.members(namedPost{})); len(attributes) != 2 {
+		t.Errorf("expected the attributes to stay unchanged, got %v", attributes)
+	}
+}
+
+// reservedPost has an attribute which snake_case turns into id
+type reservedPost struct{ namedPost }
+
+func (reservedPost) GetAttributeNames() []string {
+	return []string{"ID", "__type"}
+}
+
+func TestCheckMemberNames(t *testing.T) {
+	if err := NamingSnakeCase.CheckMemberNames(namedPost{}); err != nil {
+		t.Errorf("expected the internal __type to be left out, got %v", err)
+	}
+	err := NamingSnakeCase.CheckMemberNames(reservedPost{})
+	if err == nil || !strings.Contains(err.Error(), "member ID would be named id, which is reserved") {
+		t.Errorf("expected an error for ID, got %v", err)
+	}
+	if err := NamingAsIs.CheckMemberNames(reservedPost{}); err != nil {
+		t.Errorf("expected names which are not converted to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,523 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Structs which do not implement MarshalIdentifier or UnmarshalIdentifier can
+// describe their document with `jsonapi` struct tags instead:
+//
+//	type Post struct {
+//		ID       string    `jsonapi:"primary,posts"`
+//		Title    string    `jsonapi:"attr,title"`
+//		Draft    bool      `jsonapi:"attr,draft,omitempty"`
+//		Author   *User     `jsonapi:"relation,author"`
+//		Comments []Comment `jsonapi:"relation,comments"`
+//		TagIDs   []string  `jsonapi:"relation,tags,tags"`
+//	}
+//
+// The primary field may be a string or an integer, the type name defaults to
+// the pluralized struct name. Relations are either structs, pointers to structs
+// or slices of them, which are also included in the document, or ids as string
+// and []string. For ids the related type has to be given as third argument.
+// Empty relations tagged with omitempty are left out of the document.
+const tagName = "jsonapi"
+
+type tagFieldKind int
+
+const (
+	tagPrimary tagFieldKind = iota
+	tagAttribute
+	tagRelation
+)
+
+type tagField struct {
+	index     []int
+	kind      tagFieldKind
+	name      string
+	typeName  string
+	omitEmpty bool
+	toMany    bool
+	// idsOnly is true for string and []string relations
+	idsOnly bool
+	// relatedType is the struct type of other relations
+	relatedType reflect.Type
+}
+
+type tagSchema struct {
+	typeName   string
+	primary    *tagField
+	attributes []tagField
+	relations  []tagField
+}
+
+type cachedSchema struct {
+	schema *tagSchema
+	err    error
+}
+
+var tagSchemas sync.Map
+
+// schemaOf returns the tag schema of a struct type or nil if the struct has no
+// primary `jsonapi` tag
+func schemaOf(t reflect.Type) (*tagSchema, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	if cached, ok := tagSchemas.Load(t); ok {
+		return cached.(cachedSchema).schema, cached.(cachedSchema).err
+	}
+
+	schema, err := parseSchema(t)
+	tagSchemas.Store(t, cachedSchema{schema: schema, err: err})
+	return schema, err
+}
+
+func parseSchema(t reflect.Type) (*tagSchema, error) {
+	schema := &tagSchema{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok || tag == "-" {
+			continue
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("jsonapi tag on unexported field %s.%s", t.Name(), field.Name)
+		}
+
+		args := strings.Split(tag, ",")
+		parsed := tagField{index: field.Index}
+		switch args[0] {
+		case "primary":
+			if schema.primary != nil {
+				return nil, fmt.Errorf("%s has more than one primary field", t.Name())
+			}
+			switch field.Type.Kind() {
+			case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+				reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			default:
+				return nil, fmt.Errorf("primary field %s.%s must be a string or an integer", t.Name(), field.Name)
+			}
+			parsed.kind = tagPrimary
+			schema.typeName = Pluralize(Jsonify(t.Name()))
+			if len(args) > 1 && args[1] != "" {
+				schema.typeName = args[1]
+			}
+			schema.primary = &parsed
+		case "attr":
+			parsed.kind = tagAttribute
+			parsed.name = Jsonify(field.Name)
+			if len(args) > 1 && args[1] != "" {
+				parsed.name = args[1]
+			}
+			parsed.omitEmpty = len(args) > 2 && args[2] == "omitempty"
+			schema.attributes = append(schema.attributes, parsed)
+		case "relation":
+			parsed.kind = tagRelation
+			parsed.name = Jsonify(field.Name)
+			if len(args) > 1 && args[1] != "" {
+				parsed.name = args[1]
+			}
+			for _, arg := range args[2:] {
+				if arg == "omitempty" {
+					parsed.omitEmpty = true
+				} else if arg != "" {
+					parsed.typeName = arg
+				}
+			}
+			if err := describeRelation(t, field, &parsed); err != nil {
+				return nil, err
+			}
+			schema.relations = append(schema.relations, parsed)
+		default:
+			return nil, fmt.Errorf("unknown jsonapi tag %q on %s.%s", tag, t.Name(), field.Name)
+		}
+	}
+
+	if schema.primary == nil {
+		if len(schema.attributes) > 0 || len(schema.relations) > 0 {
+			return nil, fmt.Errorf("%s has jsonapi tags but no primary field", t.Name())
+		}
+		return nil, nil
+	}
+	return schema, nil
+}
+
+func describeRelation(owner reflect.Type, field reflect.StructField, parsed *tagField) error {
+	elem := field.Type
+	if elem.Kind() == reflect.Slice {
+		parsed.toMany = true
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.String {
+		parsed.idsOnly = true
+		if parsed.typeName == "" {
+			return fmt.Errorf("relation %s.%s holds ids and needs a type: `jsonapi:\"relation,%s,<type>\"`", owner.Name(), field.Name, parsed.name)
+		}
+		return nil
+	}
+
+	structType := elem
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("relation %s.%s must be a struct, a pointer to a struct, a string or a slice of them", owner.Name(), field.Name)
+	}
+	parsed.relatedType = structType
+	return nil
+}
+
+// relatedTypeName returns the type of the related resources. It is resolved
+// lazily, so structs can refer to each other.
+func (f tagField) relatedTypeName() string {
+	if f.typeName != "" || f.relatedType == nil {
+		return f.typeName
+	}
+	return getStructType(reflect.New(f.relatedType).Interface())
+}
+
+// taggedStruct adapts a struct with `jsonapi` tags to the marshal and
+// unmarshal interfaces
+type taggedStruct struct {
+	value  reflect.Value
+	schema *tagSchema
+}
+
+// asMarshalIdentifier returns data itself if it implements MarshalIdentifier,
+// otherwise an adapter for its struct tags
+func asMarshalIdentifier(data interface{}) (MarshalIdentifier, error) {
+	if identifier, ok := data.(MarshalIdentifier); ok {
+		return identifier, nil
+	}
+	tagged, err := newTaggedStruct(data)
+	if err != nil {
+		return nil, err
+	}
+	if tagged == nil {
+		return nil, fmt.Errorf("%T must implement MarshalIdentifier or have jsonapi struct tags", data)
+	}
+	return tagged, nil
+}
+
+func newTaggedStruct(data interface{}) (*taggedStruct, error) {
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil, errors.New("cannot marshal a nil pointer")
+		}
+		value = value.Elem()
+	}
+	schema, err := schemaOf(value.Type())
+	if err != nil || schema == nil {
+		return nil, err
+	}
+	return &taggedStruct{value: value, schema: schema}, nil
+}
+
+// GetName returns the type name of the primary tag
+func (s *taggedStruct) GetName() string {
+	return s.schema.typeName
+}
+
+// GetID formats the primary field
+func (s *taggedStruct) GetID() string {
+	field := s.value.FieldByIndex(s.schema.primary.index)
+	switch field.Kind() {
+	case reflect.String:
+		return field.String()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(field.Uint(), 10)
+	default:
+		return strconv.FormatInt(field.Int(), 10)
+	}
+}
+
+// GetAttributes returns the attribute fields
+func (s *taggedStruct) GetAttributes() map[string]interface{} {
+	attributes := make(map[string]interface{}, len(s.schema.attributes))
+	for _, attribute := range s.schema.attributes {
+		field := s.value.FieldByIndex(attribute.index)
+		if attribute.omitEmpty && field.IsZero() {
+			continue
+		}
+		attributes[attribute.name] = field.Interface()
+	}
+	return attributes
+}
+
+// GetAttributeNames returns the names of all attribute fields
+func (s *taggedStruct) GetAttributeNames() []string {
+	names := make([]string, 0, len(s.schema.attributes))
+	for _, attribute := range s.schema.attributes {
+		names = append(names, attribute.name)
+	}
+	return names
+}
+
+// GetReferences returns one reference per relation field
+func (s *taggedStruct) GetReferences() []Reference {
+	references := make([]Reference, 0, len(s.schema.relations))
+	for _, relation := range s.schema.relations {
+		if relation.omitEmpty && len(relatedValues(s.value.FieldByIndex(relation.index), relation.toMany)) == 0 {
+			continue
+		}
+		references = append(references, Reference{
+			Type:         relation.relatedTypeName(),
+			Name:         relation.name,
+			Relationship: relationshipTypeOf(relation),
+		})
+	}
+	return references
+}
+
+func relationshipTypeOf(relation tagField) RelationshipType {
+	if relation.toMany {
+		return ToManyRelationship
+	}
+	return ToOneRelationship
+}
+
+// relatedValues returns the non empty elements of a relation field
+func relatedValues(field reflect.Value, toMany bool) []reflect.Value {
+	elements := []reflect.Value{field}
+	if toMany {
+		elements = make([]reflect.Value, 0, field.Len())
+		for i := 0; i < field.Len(); i++ {
+			elements = append(elements, field.Index(i))
+		}
+	}
+
+	result := make([]reflect.Value, 0, len(elements))
+	for _, element := range elements {
+		if element.Kind() == reflect.Ptr && element.IsNil() {
+			continue
+		}
+		if element.Kind() == reflect.String && element.String() == "" {
+			continue
+		}
+		result = append(result, element)
+	}
+	return result
+}
+
+// GetReferencedIDs returns the ids of all relation fields
+func (s *taggedStruct) GetReferencedIDs() []ReferenceID {
+	ids := make([]ReferenceID, 0)
+	for _, relation := range s.schema.relations {
+		for _, element := range relatedValues(s.value.FieldByIndex(relation.index), relation.toMany) {
+			id := ReferenceID{Type: relation.relatedTypeName(), Name: relation.name, Relationship: relationshipTypeOf(relation)}
+			if relation.idsOnly {
+				id.ID = element.String()
+			} else if identifier, err := asMarshalIdentifier(element.Interface()); err == nil {
+				id.ID = identifier.GetID()
+			} else {
+				continue
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// GetReferencedStructs returns the related structs which are included in the document
+func (s *taggedStruct) GetReferencedStructs() []MarshalIdentifier {
+	structs := make([]MarshalIdentifier, 0)
+	for _, relation := range s.schema.relations {
+		if relation.idsOnly {
+			continue
+		}
+		for _, element := range relatedValues(s.value.FieldByIndex(relation.index), relation.toMany) {
+			if identifier, err := asMarshalIdentifier(element.Interface()); err == nil {
+				structs = append(structs, identifier)
+			}
+		}
+	}
+	return structs
+}
+
+// asUnmarshalIdentifier returns target itself if it implements
+// UnmarshalIdentifier, otherwise an adapter for its struct tags. target must be
+// a pointer.
+func asUnmarshalIdentifier(target interface{}) (UnmarshalIdentifier, error) {
+	if identifier, ok := target.(UnmarshalIdentifier); ok {
+		return identifier, nil
+	}
+	value := reflect.ValueOf(target)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil, errors.New("target must implement UnmarshalIdentifier interface")
+	}
+	schema, err := schemaOf(value.Type())
+	if err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		return nil, errors.New("target must implement UnmarshalIdentifier interface")
+	}
+	return &taggedStruct{value: value.Elem(), schema: schema}, nil
+}
+
+// setID parses id into an integer or string field
+func setID(field reflect.Value, id string) error {
+	if id == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(id, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %v", id, err)
+		}
+		field.SetUint(parsed)
+	default:
+		parsed, err := strconv.ParseInt(id, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid id %q: %v", id, err)
+		}
+		field.SetInt(parsed)
+	}
+	return nil
+}
+
+// SetID sets the primary field
+func (s *taggedStruct) SetID(id string) error {
+	return setID(s.value.FieldByIndex(s.schema.primary.index), id)
+}
+
+// SetAttributes sets all attribute fields which are present in attributes.
+// Values are converted by their JSON encoding, values which do not fit their
+// field are skipped.
+func (s *taggedStruct) SetAttributes(attributes map[string]interface{}) {
+	for _, attribute := range s.schema.attributes {
+		if value, ok := attributes[attribute.name]; ok {
+			s.setAttribute(attribute, value)
+		}
+	}
+}
+
+// setAttributes is SetAttributes for the unmarshal path. It fails without
+// changing any field if a value does not fit its field.
+func (s *taggedStruct) setAttributes(attributes map[string]interface{}) error {
+	for _, attribute := range s.schema.attributes {
+		value, ok := attributes[attribute.name]
+		if !ok || value == nil {
+			continue
+		}
+		field := s.value.FieldByIndex(attribute.index)
+		if err := convertAttribute(value, reflect.New(field.Type()).Interface()); err != nil {
+			return fmt.Errorf("invalid value for attribute %s: %v", attribute.name, err)
+		}
+	}
+	s.SetAttributes(attributes)
+	return nil
+}
+
+func (s *taggedStruct) setAttribute(attribute tagField, value interface{}) {
+	field := s.value.FieldByIndex(attribute.index)
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return
+	}
+	converted := reflect.New(field.Type())
+	if convertAttribute(value, converted.Interface()) == nil {
+		field.Set(converted.Elem())
+	}
+}
+
+// convertAttribute stores value in target by its JSON encoding
+func convertAttribute(value interface{}, target interface{}) error {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, target)
+}
+
+func (s *taggedStruct) relation(name string) (tagField, error) {
+	for _, relation := range s.schema.relations {
+		if relation.name == name {
+			return relation, nil
+		}
+	}
+	return tagField{}, fmt.Errorf("%s has no relationship %s", s.schema.typeName, name)
+}
+
+// newRelated returns a value of type t, which is a string, a struct or a
+// pointer to a struct, referring to id
+func newRelated(t reflect.Type, id string) (reflect.Value, error) {
+	if t.Kind() == reflect.String {
+		return reflect.ValueOf(id).Convert(t), nil
+	}
+
+	structType := t
+	if t.Kind() == reflect.Ptr {
+		structType = t.Elem()
+	}
+	pointer := reflect.New(structType)
+	target, err := asUnmarshalIdentifier(pointer.Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if err := target.SetID(id); err != nil {
+		return reflect.Value{}, err
+	}
+	if t.Kind() == reflect.Ptr {
+		return pointer, nil
+	}
+	return pointer.Elem(), nil
+}
+
+// SetToOneReferenceID sets a to-one relation field, an empty id clears it
+func (s *taggedStruct) SetToOneReferenceID(name, id string) error {
+	relation, err := s.relation(name)
+	if err != nil {
+		return err
+	}
+	if relation.toMany {
+		return fmt.Errorf("relationship %s of %s is a to-many relationship", name, s.schema.typeName)
+	}
+	field := s.value.FieldByIndex(relation.index)
+	if id == "" {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	related, err := newRelated(field.Type(), id)
+	if err != nil {
+		return err
+	}
+	field.Set(related)
+	return nil
+}
+
+// SetToManyReferenceIDs replaces a to-many relation field
+func (s *taggedStruct) SetToManyReferenceIDs(name string, IDs []map[string]interface{}) error {
+	relation, err := s.relation(name)
+	if err != nil {
+		return err
+	}
+	if !relation.toMany {
+		return fmt.Errorf("relationship %s of %s is a to-one relationship", name, s.schema.typeName)
+	}
+	field := s.value.FieldByIndex(relation.index)
+	slice := reflect.MakeSlice(field.Type(), 0, len(IDs))
+	for _, id := range IDs {
+		related, err := newRelated(field.Type().Elem(), fmt.Sprintf("%v", id["id"]))
+		if err != nil {
+			return err
+		}
+		slice = reflect.Append(slice, related)
+	}
+	field.Set(slice)
+	return nil
+}
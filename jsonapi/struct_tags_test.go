@@ -0,0 +1,127 @@
+package jsonapi
+
+import (
+	json1 "encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type taggedAuthor struct {
+	ID   int    `jsonapi:"primary,authors"`
+	Name string `jsonapi:"attr,name"`
+}
+
+type taggedPost struct {
+	ID     string        `jsonapi:"primary,posts"`
+	Title  string        `jsonapi:"attr,title"`
+	Age    int           `jsonapi:"attr,age"`
+	Draft  bool          `jsonapi:"attr,draft,omitempty"`
+	Author *taggedAuthor `jsonapi:"relation,author"`
+	TagIDs []string      `jsonapi:"relation,tags,tags"`
+}
+
+func TestMarshalTaggedStruct(t *testing.T) {
+	post := taggedPost{
+		ID:     "1",
+		Title:  "notes",
+		Age:    3,
+		Author: &taggedAuthor{ID: 7, Name: "ada"},
+		TagIDs: []string{"a", "b"},
+	}
+	encoded, err := Marshal(post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var document struct {
+		Data struct {
+			Type          string                      `json:"type"`
+			ID            string                      `json:"id"`
+			Attributes    map[string]interface{}      `json:"attributes"`
+			Relationships map[string]json1.RawMessage `json:"relationships"`
+		} `json:"data"`
+		Included []struct {
+			Type       string                 `json:"type"`
+			ID         string                 `json:"id"`
+			Attributes map[string]interface{} `json:"attributes"`
+		} `json:"included"`
+	}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		t.Fatal(err)
+	}
+
+	if document.Data.Type != "posts" || document.Data.ID != "1" {
+		t.Errorf("expected posts 1, got %s %s", document.Data.Type, document.Data.ID)
+	}
+	want := map[string]interface{}{"title": "notes", "age": float64(3)}
+	if !reflect.DeepEqual(document.Data.Attributes, want) {
+		t.Errorf("expected the attributes %v without the empty draft, got %v", want, document.Data.Attributes)
+	}
+	if author := string(document.Data.Relationships["author"]); !strings.Contains(author, `{"type":"authors","id":"7"}`) {
+		t.Errorf("expected the author linkage, got %s", author)
+	}
+	if tags := string(document.Data.Relationships["tags"]); !strings.Contains(tags, `[{"type":"tags","id":"a"},{"type":"tags","id":"b"}]`) {
+		t.Errorf("expected the tag linkage, got %s", tags)
+	}
+	if len(document.Included) != 1 || document.Included[0].Type != "authors" || document.Included[0].Attributes["name"] != "ada" {
+		t.Errorf("expected the author to be included, got %+v", document.Included)
+	}
+}
+
+func TestUnmarshalTaggedStruct(t *testing.T) {
+	var post taggedPost
+	err := Unmarshal([]byte(`{"data": {"type": "posts", "id": "1",
+		"attributes": {"title": "notes", "age": 3, "draft": true},
+		"relationships": {
+			"author": {"data": {"type": "authors", "id": "7"}},
+			"tags": {"data": [{"type": "tags", "id": "a"}, {"type": "tags", "id": "b"}]}
+		}}}`), &post)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := taggedPost{
+		ID:     "1",
+		Title:  "notes",
+		Age:    3,
+		Draft:  true,
+		Author: &taggedAuthor{ID: 7},
+		TagIDs: []string{"a", "b"},
+	}
+	if !reflect.DeepEqual(post, want) {
+		t.Errorf("expected %+v, got %+v", want, post)
+	}
+}
+
+func TestUnmarshalTaggedStructWrongAttributeType(t *testing.T) {
+	post := taggedPost{Title: "old", Age: 1}
+	err := Unmarshal([]byte(`{"data": {"type": "posts", "id": "1",
+		"attributes": {"title": "new", "age": "abc"}}}`), &post)
+	if err == nil || !strings.Contains(err.Error(), "attribute age") {
+		t.Fatalf("expected an error for the attribute age, got %v", err)
+	}
+	if post.Title != "old" || post.Age != 1 {
+		t.Errorf("expected no field to change, got %+v", post)
+	}
+}
+
+func TestTaggedStructSchemaErrors(t *testing.T) {
+	type noPrimary struct {
+		Title string `jsonapi:"attr,title"`
+	}
+	type idsWithoutType struct {
+		ID     string   `jsonapi:"primary,posts"`
+		TagIDs []string `jsonapi:"relation,tags"`
+	}
+	type unknownTag struct {
+		ID string `jsonapi:"primary,posts"`
+		X  string `jsonapi:"meta,x"`
+	}
+
+	for _, data := range []interface{}{noPrimary{}, idsWithoutType{}, unknownTag{}} {
+		if _, err := Marshal(data); err == nil {
+			t.Errorf("expected an error marshalling %T", data)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package jsonapi
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TypeConflictError is returned by CheckTypes and CheckLinkageTypes if a type
+// in a document does not match the expected one. Pointer is a JSON pointer to
+// the offending type member.
+type TypeConflictError struct {
+	Pointer  string
+	Expected string
+	Actual   string
+}
+
+func (e TypeConflictError) Error() string {
+	return fmt.Sprintf("Type %s at %s does not match the expected type %s", e.Actual, e.Pointer, e.Expected)
+}
+
+// CheckTypes checks the type of the primary data of a document against
+// resourceType, and the types of the relationship linkage against the
+// references of target, if it implements MarshalReferences or has `jsonapi`
+// struct tags. Relationships target does not know about are not checked.
+func CheckTypes(data []byte, resourceType string, target interface{}) error {
+	return NamingAsIs.CheckTypes(data, resourceType, target)
+}
+
+// CheckTypes is CheckTypes for documents whose names were converted with s.
+// resourceType is the converted type name.
+func (s NamingStrategy) CheckTypes(data []byte, resourceType string, target interface{}) error {
+	document := &Document{}
+	if err := json.Unmarshal(data, document); err != nil {
+		return err
+	}
+	if document.Data == nil {
+		return nil
+	}
+
+	references := map[string]string{}
+	if identifier, err := asMarshalIdentifier(target); err == nil {
+		if referencer, ok := identifier.(MarshalReferences); ok {
+			for _, reference := range referencer.GetReferences() {
+				references[s.MemberName(identifier, reference.Name)] = s.Name(reference.Type)
+			}
+		}
+	}
+
+	check := func(record Data, pointer string) error {
+		if record.Type != resourceType {
+			return TypeConflictError{Pointer: pointer + "/type", Expected: resourceType, Actual: record.Type}
+		}
+		for name, relationship := range record.Relationships {
+			expected, ok := references[name]
+			if !ok || expected == "" || relationship.Data == nil {
+				continue
+			}
+			if err := checkLinkage(relationship.Data, expected, pointer+"/relationships/"+name+"/data"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if document.Data.DataObject != nil {
+		return check(*document.Data.DataObject, "/data")
+	}
+	for i, record := range document.Data.DataArray {
+		if err := check(record, "/data/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckLinkageTypes checks the types of a relationship document, as sent to
+// the relationship routes, against relationshipType
+func CheckLinkageTypes(data []byte, relationshipType string) error {
+	linkage := &struct {
+		Data *RelationshipDataContainer `json:"data"`
+	}{}
+	if err := json.Unmarshal(data, linkage); err != nil {
+		return err
+	}
+	if linkage.Data == nil {
+		return nil
+	}
+	return checkLinkage(linkage.Data, relationshipType, "/data")
+}
+
+func checkLinkage(container *RelationshipDataContainer, expected, pointer string) error {
+	if container.DataObject != nil && container.DataObject.Type != expected {
+		return TypeConflictError{Pointer: pointer + "/type", Expected: expected, Actual: container.DataObject.Type}
+	}
+	for i, linkage := range container.DataArray {
+		if linkage.Type != expected {
+			return TypeConflictError{Pointer: pointer + "/" + strconv.Itoa(i) + "/type", Expected: expected, Actual: linkage.Type}
+		}
+	}
+	return nil
+}
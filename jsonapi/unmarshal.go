@@ -77,8 +77,15 @@ type EditToManyRelations interface {
 }
 
 // Unmarshal parses a JSON API compatible JSON and populates the target which
-// must implement the `UnmarshalIdentifier` interface.
+// must implement the `UnmarshalIdentifier` interface or have `jsonapi` struct tags.
 func Unmarshal(data []byte, target interface{}) error {
+	return NamingAsIs.Unmarshal(data, target)
+}
+
+// Unmarshal is Unmarshal for documents whose names were converted with s.
+// Attribute and relationship names are mapped back to the names of target,
+// see AttributeNamer.
+func (s NamingStrategy) Unmarshal(data []byte, target interface{}) error {
 	if target == nil {
 		return errors.New("target must not be nil")
 	}
@@ -99,7 +106,7 @@ func Unmarshal(data []byte, target interface{}) error {
 	}
 
 	if ctx.Data.DataObject != nil {
-		return setDataIntoTarget(ctx.Data.DataObject, target)
+		return setDataIntoTarget(ctx.Data.DataObject, target, s)
 	}
 
 	if ctx.Data.DataArray != nil {
@@ -116,8 +123,8 @@ func Unmarshal(data []byte, target interface{}) error {
 			// otherwise create a new target and append
 			var targetRecord, emptyValue reflect.Value
 			for i := 0; i < targetValue.Len(); i++ {
-				marshalCasted, ok := targetValue.Index(i).Interface().(MarshalIdentifier)
-				if !ok {
+				marshalCasted, err := asMarshalIdentifier(targetValue.Index(i).Interface())
+				if err != nil {
 					return errors.New("existing structs must implement interface MarshalIdentifier")
 				}
 				if record.ID == marshalCasted.GetID() {
@@ -128,13 +135,13 @@ func Unmarshal(data []byte, target interface{}) error {
 
 			if targetRecord == emptyValue || targetRecord.IsNil() {
 				targetRecord = reflect.New(targetType)
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), s)
 				if err != nil {
 					return err
 				}
 				targetValue = reflect.Append(targetValue, targetRecord.Elem())
 			} else {
-				err := setDataIntoTarget(&record, targetRecord.Interface())
+				err := setDataIntoTarget(&record, targetRecord.Interface(), s)
 				if err != nil {
 					return err
 				}
@@ -147,16 +154,20 @@ func Unmarshal(data []byte, target interface{}) error {
 	return nil
 }
 
-func setDataIntoTarget(data *Data, target interface{}) error {
-	castedTarget, ok := target.(UnmarshalIdentifier)
-	if !ok {
-		return errors.New("target must implement UnmarshalIdentifier interface")
+func setDataIntoTarget(data *Data, target interface{}, naming NamingStrategy) error {
+	castedTarget, err := asUnmarshalIdentifier(target)
+	if err != nil {
+		return err
 	}
 
 	if data.Type == "" {
 		return errors.New("invalid record, no type was specified")
 	}
 
+	if err := naming.restoreNames(data, castedTarget); err != nil {
+		return err
+	}
+
 	//var err error
 	//err := checkType(data.Type, castedTarget)
 	//if err != nil {
@@ -166,7 +177,13 @@ func setDataIntoTarget(data *Data, target interface{}) error {
 	if data.Attributes != nil {
 		m := make(map[string]interface{})
 		json.Unmarshal(data.Attributes, &m)
-		castedTarget.SetAttributes(m)
+		if tagged, ok := castedTarget.(*taggedStruct); ok {
+			if err := tagged.setAttributes(m); err != nil {
+				return err
+			}
+		} else {
+			castedTarget.SetAttributes(m)
+		}
 		//err = json.Unmarshal(data.Attributes, castedTarget)
 		//if err != nil {
 		//	return err
@@ -0,0 +1,117 @@
+package api2go
+
+import (
+	"net/http"
+	"strings"
+)
+
+// setMemberNames records how the attributes and relationships of res are
+// named in documents, by the naming strategy or the resource itself, see
+// jsonapi.MemberNamer
+func (res *resource) setMemberNames() {
+	res.memberNames = res.api.info.naming.MemberNames(res.prototype)
+	res.documentNames = make(map[string]string, len(res.memberNames))
+	for documentName, name := range res.memberNames {
+		res.documentNames[name] = documentName
+	}
+}
+
+// listRequest builds the Request for listing res, with the member names in
+// the sort and filter parameters mapped to the names res uses
+func (res *resource) listRequest(c APIContexter, r *http.Request, name string) (Request, Span) {
+	request, span := tracedRequest(c, r, name)
+	res.mapQueryNames(request.QueryParams)
+	return request, span
+}
+
+func (res *resource) mapQueryNames(params map[string][]string) {
+	if sort, ok := params["sort"]; ok {
+		mapped := make([]string, 0, len(sort))
+		for _, field := range sort {
+			field = strings.TrimSpace(field)
+			if strings.HasPrefix(field, "-") {
+				mapped = append(mapped, "-"+res.ownName(field[1:]))
+			} else {
+				mapped = append(mapped, res.ownName(field))
+			}
+		}
+		params["sort"] = mapped
+	}
+
+	renamed := map[string]string{}
+	for key := range params {
+		if matches := queryFilterRegex.FindStringSubmatch(key); matches != nil {
+			if name := res.ownName(matches[1]); name != matches[1] {
+				renamed[key] = "filter[" + name + "]"
+			}
+		}
+	}
+	for key, mappedKey := range renamed {
+		params[mappedKey] = params[key]
+		delete(params, key)
+	}
+}
+
+// ownName maps a member name from a document to the name res uses. Only the
+// first part of a path like "author.name" belongs to res.
+func (res *resource) ownName(documentName string) string {
+	first, rest, nested := strings.Cut(documentName, ".")
+	name, ok := res.memberNames[first]
+	if !ok {
+		return documentName
+	}
+	if nested {
+		return name + "." + rest
+	}
+	return name
+}
+
+// documentName maps a member name of res to its name in documents
+func (res *resource) documentName(name string) string {
+	first, rest, nested := strings.Cut(name, ".")
+	documentName, ok := res.documentNames[first]
+	if !ok {
+		return name
+	}
+	if nested {
+		return documentName + "." + rest
+	}
+	return documentName
+}
+
+// documentErrors maps the member names in the source pointers and filter
+// parameters of an HTTPError to their names in documents
+func (res *resource) documentErrors(err error) error {
+	httpError, ok := err.(HTTPError)
+	if !ok || len(httpError.Errors) == 0 {
+		return err
+	}
+
+	errs := make([]Error, len(httpError.Errors))
+	for i, e := range httpError.Errors {
+		if e.Source != nil {
+			source := *e.Source
+			source.Pointer = res.documentPointer(source.Pointer)
+			if matches := queryFilterRegex.FindStringSubmatch(source.Parameter); matches != nil {
+				source.Parameter = "filter[" + res.documentName(matches[1]) + "]"
+			}
+			e.Source = &source
+		}
+		errs[i] = e
+	}
+	httpError.Errors = errs
+	return httpError
+}
+
+// documentPointer maps the member in pointers like "/data/attributes/name"
+// or "/data/0/relationships/name/data"
+func (res *resource) documentPointer(pointer string) string {
+	segments := strings.Split(pointer, "/")
+	for i := 1; i+1 < len(segments); i++ {
+		if segments[i] == "attributes" || segments[i] == "relationships" {
+			segments[i+1] = res.documentName(segments[i+1])
+			break
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,83 @@
+package api2go
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// namedResource has the column created_at named createdAt and the
+// relationship author_id named author in documents
+func namedResource() *resource {
+	res := &resource{
+		memberNames: map[string]string{"createdAt": "created_at", "author": "author_id", "title": "title"},
+	}
+	res.documentNames = make(map[string]string, len(res.memberNames))
+	for documentName, name := range res.memberNames {
+		res.documentNames[name] = documentName
+	}
+	return res
+}
+
+func TestMapQueryNames(t *testing.T) {
+	params := map[string][]string{
+		"sort":                {"-createdAt", " title", "unknown"},
+		"filter[createdAt]":   {"2020"},
+		"filter[author.name]": {"ada"},
+		"filter[title]":       {"notes"},
+		"filter[unknown]":     {"x"},
+		"page[number]":        {"1"},
+	}
+	namedResource().mapQueryNames(params)
+
+	want := map[string][]string{
+		"sort":                   {"-created_at", "title", "unknown"},
+		"filter[created_at]":     {"2020"},
+		"filter[author_id.name]": {"ada"},
+		"filter[title]":          {"notes"},
+		"filter[unknown]":        {"x"},
+		"page[number]":           {"1"},
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("expected the parameters %v, got %v", want, params)
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	res := namedResource()
+	httpError := NewHTTPError(nil, "invalid", http.StatusBadRequest)
+	httpError.Errors = []Error{
+		{Title: "attribute", Source: &ErrorSource{Pointer: "/data/attributes/created_at"}},
+		{Title: "relationship", Source: &ErrorSource{Pointer: "/data/0/relationships/author_id/data"}},
+		{Title: "filter", Source: &ErrorSource{Parameter: "filter[author_id.name]"}},
+		{Title: "unknown", Source: &ErrorSource{Pointer: "/data/attributes/unknown"}},
+		{Title: "no source"},
+	}
+	source := httpError.Errors[0].Source
+
+	mapped, ok := res.documentErrors(httpError).(HTTPError)
+	if !ok {
+		t.Fatal("expected an HTTPError")
+	}
+	want := []*ErrorSource{
+		{Pointer: "/data/attributes/createdAt"},
+		{Pointer: "/data/0/relationships/author/data"},
+		{Parameter: "filter[author.name]"},
+		{Pointer: "/data/attributes/unknown"},
+		nil,
+	}
+	for i, e := range mapped.Errors {
+		if !reflect.DeepEqual(e.Source, want[i]) {
+			t.Errorf("%s: expected the source %+v, got %+v", e.Title, want[i], e.Source)
+		}
+	}
+	if source.Pointer != "/data/attributes/created_at" {
+		t.Errorf("expected the source of the original error to stay unchanged, got %s", source.Pointer)
+	}
+
+	plain := errors.New("plain")
+	if err := res.documentErrors(plain); err != plain {
+		t.Errorf("expected other errors to be returned unchanged, got %v", err)
+	}
+}
@@ -0,0 +1,792 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// MemoryStore keeps the rows of any number of Api2GoModel resources in memory.
+// It is safe for concurrent use. Create one data source per resource with
+// Resource, all of them share the rows and relationship data of the store.
+//
+//	store := api2go.NewMemoryStore()
+//	api.AddResource(user, store.Resource(user))
+//	api.AddResource(tag, store.Resource(tag))
+//	err := store.Seed(fixtures)
+type MemoryStore struct {
+	mutex  sync.RWMutex
+	models map[string]Api2GoModel
+	tables map[string]*memoryTable
+	// join data of to-many relations, by join table name
+	joins map[string]map[memoryJoin]bool
+}
+
+type memoryTable struct {
+	rows  map[string]map[string]interface{}
+	order []string
+}
+
+type memoryJoin struct {
+	subject string
+	object  string
+}
+
+// NewMemoryStore returns an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		models: make(map[string]Api2GoModel),
+		tables: make(map[string]*memoryTable),
+		joins:  make(map[string]map[memoryJoin]bool),
+	}
+}
+
+// Resource registers the schema of model and returns a data source for it
+func (s *MemoryStore) Resource(model Api2GoModel) *MemoryResource {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.models[model.GetTableName()] = model
+	if _, ok := s.tables[model.GetTableName()]; !ok {
+		s.tables[model.GetTableName()] = &memoryTable{rows: make(map[string]map[string]interface{})}
+	}
+	return &MemoryResource{store: s, model: model}
+}
+
+// Seed loads the primary data and the included resources of a JSON:API document
+// into the store. All types in the document must have been registered with
+// Resource. Relationships are stored as they are given, the related resources
+// do not need to exist.
+func (s *MemoryStore) Seed(data []byte) error {
+	document := &jsonapi.Document{}
+	if err := jsonLib.Unmarshal(data, document); err != nil {
+		return err
+	}
+	if document.Data == nil {
+		return errors.New(`document has no "data"`)
+	}
+
+	records := make([]jsonapi.Data, 0)
+	if document.Data.DataObject != nil {
+		records = append(records, *document.Data.DataObject)
+	}
+	records = append(records, document.Data.DataArray...)
+	records = append(records, document.Included...)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, record := range records {
+		model, ok := s.models[record.Type]
+		if !ok {
+			return fmt.Errorf("no resource registered for type %s", record.Type)
+		}
+		if record.ID == "" {
+			return fmt.Errorf("seed record of type %s has no id", record.Type)
+		}
+
+		row := make(map[string]interface{})
+		if len(record.Attributes) > 0 {
+			if err := jsonLib.Unmarshal(record.Attributes, &row); err != nil {
+				return err
+			}
+		}
+		row["reference_id"] = record.ID
+		if model.HasColumn("version") {
+			if _, ok := row["version"]; !ok {
+				row["version"] = int64(1)
+			}
+		}
+		transformNumbersDict(row)
+
+		for name, relationship := range record.Relationships {
+			if relationship.Data == nil {
+				continue
+			}
+			ids := make([]string, 0)
+			if relationship.Data.DataObject != nil {
+				ids = append(ids, relationship.Data.DataObject.ID)
+			}
+			for _, linkage := range relationship.Data.DataArray {
+				ids = append(ids, linkage.ID)
+			}
+			if err := s.setRelation(model, row, name, ids); err != nil {
+				return err
+			}
+		}
+
+		s.put(record.Type, row)
+	}
+
+	return nil
+}
+
+// setRelation stores the related ids of the relationship name of row.
+// The caller must hold the write lock.
+func (s *MemoryStore) setRelation(model Api2GoModel, row map[string]interface{}, name string, ids []string) error {
+	table := model.GetTableName()
+	self := fmt.Sprintf("%v", row["reference_id"])
+
+	for _, relation := range model.GetRelations() {
+		toMany := relation.Relation.ToMany()
+
+		if relation.GetSubject() == table && relation.GetObjectName() == name {
+			if !toMany {
+				if len(ids) == 0 {
+					row[name] = nil
+				} else {
+					row[name] = ids[0]
+				}
+				return nil
+			}
+			s.replaceJoins(relation, self, ids, true)
+			return nil
+		}
+
+		if relation.GetObject() == table && relation.GetSubjectName() == name {
+			if !toMany {
+				// the foreign key lives in the rows of the subject
+				subjects := s.tables[relation.GetSubject()]
+				if subjects == nil {
+					return fmt.Errorf("no resource registered for type %s", relation.GetSubject())
+				}
+				for _, subject := range subjects.rows {
+					if subject[relation.GetObjectName()] == self {
+						subject[relation.GetObjectName()] = nil
+					}
+				}
+				for _, id := range ids {
+					if subject, ok := subjects.rows[id]; ok {
+						subject[relation.GetObjectName()] = self
+					}
+				}
+				return nil
+			}
+			s.replaceJoins(relation, self, ids, false)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("there is no relationship with the name %s on %s", name, table)
+}
+
+func (s *MemoryStore) joinSet(relation TableRelation) map[memoryJoin]bool {
+	name := relation.GetJoinTableName()
+	if s.joins[name] == nil {
+		s.joins[name] = make(map[memoryJoin]bool)
+	}
+	return s.joins[name]
+}
+
+func (s *MemoryStore) replaceJoins(relation TableRelation, self string, ids []string, subjectSide bool) {
+	joins := s.joinSet(relation)
+	for join := range joins {
+		if (subjectSide && join.subject == self) || (!subjectSide && join.object == self) {
+			delete(joins, join)
+		}
+	}
+	s.addJoins(relation, self, ids, subjectSide)
+}
+
+func (s *MemoryStore) addJoins(relation TableRelation, self string, ids []string, subjectSide bool) {
+	joins := s.joinSet(relation)
+	for _, id := range ids {
+		if subjectSide {
+			joins[memoryJoin{subject: self, object: id}] = true
+		} else {
+			joins[memoryJoin{subject: id, object: self}] = true
+		}
+	}
+}
+
+func (s *MemoryStore) deleteJoins(relation TableRelation, self string, ids []string, subjectSide bool) {
+	joins := s.joinSet(relation)
+	for _, id := range ids {
+		if subjectSide {
+			delete(joins, memoryJoin{subject: self, object: id})
+		} else {
+			delete(joins, memoryJoin{subject: id, object: self})
+		}
+	}
+}
+
+// memorySnapshot holds the relationship data a write may change, so that it
+// can be restored if the write fails
+type memorySnapshot struct {
+	joins map[string]map[memoryJoin]bool
+	keys  []memoryKey
+}
+
+// memoryKey is the foreign key value of a row of another table
+type memoryKey struct {
+	row    map[string]interface{}
+	column string
+	value  interface{}
+}
+
+// snapshot copies the join data of the relations of model and the foreign keys
+// other tables hold to its rows. The caller must hold the write lock.
+func (s *MemoryStore) snapshot(model Api2GoModel) *memorySnapshot {
+	table := model.GetTableName()
+	result := &memorySnapshot{joins: make(map[string]map[memoryJoin]bool)}
+
+	for _, relation := range model.GetRelations() {
+		toMany := relation.GetRelation() == "has_many" || relation.GetRelation() == "has_many_and_belongs_to_many"
+		if toMany {
+			name := relation.GetJoinTableName()
+			if _, ok := result.joins[name]; ok {
+				continue
+			}
+			joins := make(map[memoryJoin]bool, len(s.joins[name]))
+			for join := range s.joins[name] {
+				joins[join] = true
+			}
+			result.joins[name] = joins
+			continue
+		}
+		if relation.GetObject() != table {
+			continue
+		}
+		if subjects, ok := s.tables[relation.GetSubject()]; ok {
+			for _, row := range subjects.rows {
+				result.keys = append(result.keys, memoryKey{row: row, column: relation.GetObjectName(), value: row[relation.GetObjectName()]})
+			}
+		}
+	}
+	return result
+}
+
+// restore undoes all changes to the data copied by snapshot. The caller must
+// hold the write lock.
+func (s *MemoryStore) restore(snapshot *memorySnapshot) {
+	for name, joins := range snapshot.joins {
+		s.joins[name] = joins
+	}
+	for _, key := range snapshot.keys {
+		key.row[key.column] = key.value
+	}
+}
+
+// put stores row, the caller must hold the write lock
+func (s *MemoryStore) put(table string, row map[string]interface{}) {
+	t := s.tables[table]
+	id := fmt.Sprintf("%v", row["reference_id"])
+	if _, exists := t.rows[id]; !exists {
+		t.order = append(t.order, id)
+	}
+	t.rows[id] = row
+}
+
+// read returns a copy of the row with all related ids, the caller must hold
+// the read lock
+func (s *MemoryStore) read(model Api2GoModel, row map[string]interface{}) Api2GoModel {
+	table := model.GetTableName()
+	self := fmt.Sprintf("%v", row["reference_id"])
+
+	data := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		data[k] = v
+	}
+
+	for _, relation := range model.GetRelations() {
+		toMany := relation.Relation.ToMany()
+
+		if relation.GetSubject() == table && toMany {
+			ids := make([]string, 0)
+			for join := range s.joins[relation.GetJoinTableName()] {
+				if join.subject == self {
+					ids = append(ids, join.object)
+				}
+			}
+			sort.Strings(ids)
+			data[relation.GetObjectName()] = ids
+		}
+
+		if relation.GetObject() == table {
+			ids := make([]string, 0)
+			if toMany {
+				for join := range s.joins[relation.GetJoinTableName()] {
+					if join.object == self {
+						ids = append(ids, join.subject)
+					}
+				}
+			} else if subjects, ok := s.tables[relation.GetSubject()]; ok {
+				for _, id := range subjects.order {
+					if subjects.rows[id][relation.GetObjectName()] == self {
+						ids = append(ids, id)
+					}
+				}
+			}
+			sort.Strings(ids)
+			data[relation.GetSubjectName()] = ids
+		}
+	}
+
+	return NewApi2GoModelWithData(table, model.GetColumns(), model.GetDefaultPermission(), model.GetRelations(), data)
+}
+
+// MemoryResource is the data source of one resource in a MemoryStore.
+// It implements CRUD, FindAll and PaginatedFindAll with the same sort, filter
+// and page query parameters as SQLResource.
+type MemoryResource struct {
+	store *MemoryStore
+	model Api2GoModel
+}
+
+// Compile time checks
+var (
+	_ CRUD                   = &MemoryResource{}
+	_ FindAll                = &MemoryResource{}
+	_ PaginatedFindAll       = &MemoryResource{}
+	_ ObjectInitializer      = &MemoryResource{}
+	_ RelatedFinder          = &MemoryResource{}
+	_ PaginatedRelatedFinder = &MemoryResource{}
+)
+
+// InitializeObject sets name, columns and relations of the model on objects
+// created for unmarshalling, so relationships in the request can be resolved
+func (m *MemoryResource) InitializeObject(obj interface{}) {
+	if model, ok := obj.(*Api2GoModel); ok {
+		model.typeName = m.model.GetTableName()
+		model.columns = m.model.GetColumns()
+		model.relations = m.model.GetRelations()
+		model.defaultPermission = m.model.GetDefaultPermission()
+	}
+}
+
+func (m *MemoryResource) table() *memoryTable {
+	return m.store.tables[m.model.GetTableName()]
+}
+
+func (m *MemoryResource) known(name string) bool {
+	column, ok := m.model.GetColumnMap()[name]
+	return ok && name != "id" && !column.ExcludeFromApi
+}
+
+// relatedIDs returns the ids related to the parent resource through the relation
+// called relationName on the parent's side, the caller must hold the read lock
+func (m *MemoryResource) relatedIDs(parentType, parentID, relationName string) (map[string]bool, bool) {
+	table := m.model.GetTableName()
+	for _, relation := range m.model.GetRelations() {
+		if relation.GetSubject() == parentType && relation.GetObject() == table && relation.GetObjectName() == relationName {
+			parent, ok := m.store.tables[parentType]
+			if !ok {
+				return nil, false
+			}
+			return m.collect(relation, parent.rows[parentID], parentID, true), true
+		}
+		if relation.GetObject() == parentType && relation.GetSubject() == table && relation.GetSubjectName() == relationName {
+			return m.collect(relation, nil, parentID, false), true
+		}
+	}
+	return nil, false
+}
+
+// collect returns the ids on the other side of relation for the row with the id
+// self. parentIsSubject tells on which side of the relation self is.
+func (m *MemoryResource) collect(relation TableRelation, parent map[string]interface{}, self string, parentIsSubject bool) map[string]bool {
+	result := make(map[string]bool)
+	toMany := relation.Relation.ToMany()
+
+	if toMany {
+		for join := range m.store.joins[relation.GetJoinTableName()] {
+			if parentIsSubject && join.subject == self {
+				result[join.object] = true
+			} else if !parentIsSubject && join.object == self {
+				result[join.subject] = true
+			}
+		}
+		return result
+	}
+
+	if parentIsSubject {
+		if parent != nil && parent[relation.GetObjectName()] != nil {
+			result[fmt.Sprintf("%v", parent[relation.GetObjectName()])] = true
+		}
+		return result
+	}
+
+	for id, row := range m.table().rows {
+		if row[relation.GetObjectName()] == self {
+			result[id] = true
+		}
+	}
+	return result
+}
+
+// compareValues orders nil first, numbers numerically and everything else by
+// its string representation
+func compareValues(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		default:
+			return 1
+		}
+	}
+
+	numberA, okA := toFloat(a)
+	numberB, okB := toFloat(b)
+	if okA && okB {
+		switch {
+		case numberA < numberB:
+			return -1
+		case numberA > numberB:
+			return 1
+		}
+		return 0
+	}
+
+	stringA, stringB := fmt.Sprintf("%v", a), fmt.Sprintf("%v", b)
+	switch {
+	case stringA < stringB:
+		return -1
+	case stringA > stringB:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseFloat(v, 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+func (m *MemoryResource) find(req Request, parent *relatedParent) (uint, []Api2GoModel, error) {
+	options, err := parseListOptions(req, m.known)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	m.store.mutex.RLock()
+	defer m.store.mutex.RUnlock()
+
+	var related map[string]bool
+	if parent != nil {
+		var found bool
+		related, found = m.relatedIDs(parent.typeName, parent.id, parent.relation)
+		if !found {
+			return 0, nil, parent.notFound(m.model.GetTableName())
+		}
+	}
+
+	table := m.table()
+	rows := make([]map[string]interface{}, 0, len(table.order))
+	for _, id := range table.order {
+		if related != nil && !related[id] {
+			continue
+		}
+		row := table.rows[id]
+		matches := true
+		for _, filter := range options.filters {
+			found := false
+			for _, value := range filter.values {
+				if row[filter.name] != nil && fmt.Sprintf("%v", row[filter.name]) == value {
+					found = true
+					break
+				}
+			}
+			if !found {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+
+	if len(options.sort) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			for _, field := range options.sort {
+				compared := compareValues(rows[i][field.name], rows[j][field.name])
+				if compared == 0 {
+					continue
+				}
+				if field.descending {
+					return compared > 0
+				}
+				return compared < 0
+			}
+			return false
+		})
+	}
+
+	count := uint(len(rows))
+	if options.offset >= uint64(len(rows)) {
+		rows = rows[:0]
+	} else {
+		rows = rows[options.offset:]
+	}
+	if options.limit > 0 && options.limit < uint64(len(rows)) {
+		rows = rows[:options.limit]
+	}
+
+	result := make([]Api2GoModel, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, m.store.read(m.model, row))
+	}
+	return count, result, nil
+}
+
+// FindAll returns all rows matching the sort, filter and page query parameters
+func (m *MemoryResource) FindAll(req Request) (Responder, error) {
+	_, models, err := m.find(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// PaginatedFindAll returns one page of rows and the total count
+func (m *MemoryResource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	count, models, err := m.find(req, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// FindRelated returns the rows related to the parent resource
+func (m *MemoryResource) FindRelated(parentType, parentID, relationName string, req Request) (Responder, error) {
+	_, models, err := m.find(req, &relatedParent{typeName: parentType, id: parentID, relation: relationName})
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// PaginatedFindRelated returns one page of the rows related to the parent resource
+func (m *MemoryResource) PaginatedFindRelated(parentType, parentID, relationName string, req Request) (uint, Responder, error) {
+	count, models, err := m.find(req, &relatedParent{typeName: parentType, id: parentID, relation: relationName})
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+func (m *MemoryResource) notFound(ID string) error {
+	return NewHTTPError(nil, fmt.Sprintf("%s with id %s not found", m.model.GetTableName(), ID), http.StatusNotFound)
+}
+
+// FindOne returns the row with the given id
+func (m *MemoryResource) FindOne(ID string, req Request) (Responder, error) {
+	m.store.mutex.RLock()
+	defer m.store.mutex.RUnlock()
+
+	row, ok := m.table().rows[ID]
+	if !ok {
+		return nil, m.notFound(ID)
+	}
+	return &Response{Res: m.store.read(m.model, row), Code: http.StatusOK}, nil
+}
+
+// writeRelations stores the to-many relationships set by SetToManyReferenceIDs,
+// AddToManyIDs and DeleteToManyIDs, the caller must hold the write lock
+func (m *MemoryResource) writeRelations(model *Api2GoModel, row map[string]interface{}) error {
+	table := m.model.GetTableName()
+	self := fmt.Sprintf("%v", row["reference_id"])
+
+	for _, relation := range m.model.GetRelations() {
+		names := make([]string, 0, 2)
+		if relation.GetSubject() == table {
+			names = append(names, relation.GetObjectName())
+		}
+		if relation.GetObject() == table {
+			names = append(names, relation.GetSubjectName())
+		}
+
+		for _, name := range names {
+			subjectSide := relation.GetSubject() == table && name == relation.GetObjectName()
+			toMany := relation.Relation.ToMany()
+
+			if ids, ok := referenceIDsOf(model.data[name]); ok {
+				if err := m.store.setRelation(m.model, row, name, ids); err != nil {
+					return err
+				}
+			}
+			if !toMany {
+				continue
+			}
+			if ids, ok := model.AddIncludes[name]; ok {
+				m.store.addJoins(relation, self, ids, subjectSide)
+			}
+			if ids, ok := model.DeleteIncludes[name]; ok {
+				m.store.deleteJoins(relation, self, ids, subjectSide)
+			}
+		}
+	}
+	return nil
+}
+
+// attributes returns the values of model which are stored in the row,
+// relationship data is stored separately
+func (m *MemoryResource) attributes(model *Api2GoModel) map[string]interface{} {
+	result := make(map[string]interface{})
+	for k, v := range model.data {
+		if _, isRelation := referenceIDsOf(v); isRelation {
+			continue
+		}
+		if _, isList := v.([]string); isList {
+			continue
+		}
+		if k == "__type" {
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
+
+// Create stores a new row. Client generated ids are kept, those which are
+// taken already fail with 409 Conflict.
+func (m *MemoryResource) Create(obj interface{}, req Request) (Responder, error) {
+	model, err := toModelPointer(obj)
+	if err != nil {
+		return nil, err
+	}
+	if model.data == nil {
+		model.data = make(map[string]interface{})
+	}
+	clientID, _ := model.data["reference_id"].(string)
+	if err := model.BeforeCreate(); err != nil {
+		return nil, err
+	}
+	if clientID != "" {
+		model.data["reference_id"] = clientID
+	}
+
+	row := m.attributes(model)
+	row["reference_id"] = fmt.Sprintf("%v", model.data["reference_id"])
+	if m.model.HasColumn("version") {
+		row["version"] = int64(1)
+	}
+
+	ID := fmt.Sprintf("%v", row["reference_id"])
+
+	m.store.mutex.Lock()
+	defer m.store.mutex.Unlock()
+
+	if _, exists := m.table().rows[ID]; exists {
+		conflict := fmt.Errorf("%s with id %s already exists", m.model.GetTableName(), ID)
+		return nil, NewHTTPError(conflict, conflict.Error(), http.StatusConflict)
+	}
+	snapshot := m.store.snapshot(m.model)
+	m.store.put(m.model.GetTableName(), row)
+	if err := m.writeRelations(model, row); err != nil {
+		m.store.restore(snapshot)
+		m.remove(ID)
+		return nil, err
+	}
+
+	return &Response{Res: m.store.read(m.model, row), Code: http.StatusCreated}, nil
+}
+
+// Update stores changed attributes and relationships. If a relationship can
+// not be stored, no change is kept.
+func (m *MemoryResource) Update(obj interface{}, req Request) (Responder, error) {
+	model, err := toModelPointer(obj)
+	if err != nil {
+		return nil, err
+	}
+	ID := model.GetID()
+
+	m.store.mutex.Lock()
+	defer m.store.mutex.Unlock()
+
+	existing, ok := m.table().rows[ID]
+	if !ok {
+		return nil, m.notFound(ID)
+	}
+
+	row := make(map[string]interface{}, len(existing))
+	for k, v := range existing {
+		row[k] = v
+	}
+	for k, change := range model.GetChanges() {
+		if k == "reference_id" || k == "version" || k == "__type" {
+			continue
+		}
+		if _, isList := change.NewValue.([]string); isList {
+			continue
+		}
+		if _, isRelation := referenceIDsOf(change.NewValue); isRelation {
+			continue
+		}
+		row[k] = change.NewValue
+	}
+	if m.model.HasColumn("version") {
+		row["version"] = toInt64(existing["version"]) + 1
+	}
+
+	snapshot := m.store.snapshot(m.model)
+	if err := m.writeRelations(model, row); err != nil {
+		m.store.restore(snapshot)
+		return nil, err
+	}
+	m.store.put(m.model.GetTableName(), row)
+
+	return &Response{Res: m.store.read(m.model, row), Code: http.StatusOK}, nil
+}
+
+// Delete removes the row and its relationship data
+func (m *MemoryResource) Delete(id string, req Request) (Responder, error) {
+	m.store.mutex.Lock()
+	defer m.store.mutex.Unlock()
+
+	if _, ok := m.table().rows[id]; !ok {
+		return nil, m.notFound(id)
+	}
+	m.remove(id)
+
+	return &Response{Code: http.StatusNoContent}, nil
+}
+
+// remove deletes the row and its relationship data, has_one references to it
+// are cleared. The caller must hold the write lock.
+func (m *MemoryResource) remove(id string) {
+	table := m.table()
+	name := m.model.GetTableName()
+	for _, relation := range m.model.GetRelations() {
+		for join := range m.store.joins[relation.GetJoinTableName()] {
+			if (relation.GetSubject() == name && join.subject == id) || (relation.GetObject() == name && join.object == id) {
+				delete(m.store.joins[relation.GetJoinTableName()], join)
+			}
+		}
+		if relation.GetObject() == name && relation.GetRelation() == "has_one" {
+			if subjects, ok := m.store.tables[relation.GetSubject()]; ok {
+				for _, subject := range subjects.rows {
+					if subject[relation.GetObjectName()] == id {
+						subject[relation.GetObjectName()] = nil
+					}
+				}
+			}
+		}
+	}
+
+	delete(table.rows, id)
+	for i, existing := range table.order {
+		if existing == id {
+			table.order = append(table.order[:i], table.order[i+1:]...)
+			break
+		}
+	}
+}
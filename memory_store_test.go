@@ -0,0 +1,273 @@
+package api2go_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+)
+
+// memoryTestModels returns user, tag and post. Users have many tags, posts
+// belong to users and comments, which the store does not know, have one user.
+func memoryTestModels() map[string]api2go.Api2GoModel {
+	columns := func(extra ...api2go.ColumnInfo) []api2go.ColumnInfo {
+		return append([]api2go.ColumnInfo{
+			{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+			{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+			{ColumnName: "version", DataType: "int(11)", IsNullable: true},
+		}, extra...)
+	}
+	tags := api2go.NewTableRelation("user", "has_many", "tag")
+	posts := api2go.NewTableRelation("post", "belongs_to", "user")
+	comments := api2go.NewTableRelation("comment", "has_one", "user")
+
+	return map[string]api2go.Api2GoModel{
+		"user": api2go.NewApi2GoModel("user", columns(
+			api2go.ColumnInfo{ColumnName: "name", DataType: "varchar(100)", IsNullable: true},
+			api2go.ColumnInfo{ColumnName: "age", DataType: "int(11)", IsNullable: true},
+		), 0, []api2go.TableRelation{tags, posts, comments}),
+		"tag": api2go.NewApi2GoModel("tag", columns(
+			api2go.ColumnInfo{ColumnName: "label", DataType: "varchar(100)", IsNullable: true},
+		), 0, []api2go.TableRelation{tags}),
+		"post": api2go.NewApi2GoModel("post", columns(
+			api2go.ColumnInfo{ColumnName: "title", DataType: "varchar(100)", IsNullable: true},
+		), 0, []api2go.TableRelation{posts}),
+	}
+}
+
+// memoryTestAPI serves user, tag and post from a new MemoryStore
+func memoryTestAPI(t *testing.T) (*api2go.API, *api2go.MemoryStore, map[string]*api2go.MemoryResource) {
+	t.Helper()
+	models := memoryTestModels()
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPI("v1")
+	resources := make(map[string]*api2go.MemoryResource)
+	for _, name := range []string{"user", "tag", "post"} {
+		resources[name] = store.Resource(models[name])
+		api.AddResource(models[name], resources[name])
+	}
+	return api, store, resources
+}
+
+// memoryTestSeed holds two users, three tags and two posts
+const memoryTestSeed = `{
+	"data": [
+		{"type": "user", "id": "u1", "attributes": {"name": "ada", "age": 36},
+			"relationships": {"tag_id": {"data": [{"type": "tag", "id": "t1"}, {"type": "tag", "id": "t2"}]}}},
+		{"type": "user", "id": "u2", "attributes": {"name": "grace", "age": 85}}
+	],
+	"included": [
+		{"type": "tag", "id": "t1", "attributes": {"label": "a"}},
+		{"type": "tag", "id": "t2", "attributes": {"label": "b"}},
+		{"type": "tag", "id": "t3", "attributes": {"label": "c"}},
+		{"type": "post", "id": "p1", "attributes": {"title": "notes"},
+			"relationships": {"user_id": {"data": {"type": "user", "id": "u1"}}}},
+		{"type": "post", "id": "p2", "attributes": {"title": "drafts"}}
+	]
+}`
+
+func TestMemoryStoreSeed(t *testing.T) {
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient{t, api.Handler()}
+
+	user := c.do("GET", "user/u1", nil).expect(http.StatusOK)
+	if user.attribute("name") != "ada" || user.attribute("version") != "1" {
+		t.Errorf("unexpected attributes %v", user.record().Attributes)
+	}
+	if records := c.do("GET", "user/u1/tag_id", nil).expect(http.StatusOK).records(); len(records) != 2 {
+		t.Errorf("expected 2 tags of u1, got %d", len(records))
+	}
+	if records := c.do("GET", "user/u1/post_id", nil).expect(http.StatusOK).records(); len(records) != 1 || records[0].ID != "p1" {
+		t.Errorf("expected the post p1 of u1, got %v", records)
+	}
+
+	for _, seed := range []string{
+		`{}`,
+		`{"data": {"type": "unknown", "id": "x"}}`,
+		`{"data": {"type": "user", "attributes": {"name": "anonymous"}}}`,
+		`{"data": {"type": "user", "id": "u3", "relationships": {"unknown": {"data": []}}}}`,
+	} {
+		if err := store.Seed([]byte(seed)); err == nil {
+			t.Errorf("expected an error seeding %s", seed)
+		}
+	}
+}
+
+func TestMemoryResourceCRUD(t *testing.T) {
+	api, _, _ := memoryTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	created := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada", "age": 36}, nil)).expect(http.StatusCreated)
+	id := created.record().ID
+	if created.attribute("version") != "1" {
+		t.Errorf("expected version 1, got %s", created.attribute("version"))
+	}
+
+	updated := c.do("PATCH", "user/"+id, resource("user", id, map[string]interface{}{"name": "grace"}, nil)).expect(http.StatusOK)
+	if updated.attribute("name") != "grace" || updated.attribute("age") != "36" || updated.attribute("version") != "2" {
+		t.Errorf("expected only the name and the version to change, got %v", updated.record().Attributes)
+	}
+
+	c.do("POST", "user", resource("user", id, map[string]interface{}{"name": "taken"}, nil)).expect(http.StatusConflict)
+	c.do("DELETE", "user/"+id, nil).expect(http.StatusNoContent)
+	c.do("GET", "user/"+id, nil).expect(http.StatusNotFound)
+	c.do("DELETE", "user/"+id, nil).expect(http.StatusNotFound)
+}
+
+func TestMemoryResourceListOptions(t *testing.T) {
+	api, _, _ := memoryTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	for _, label := range []string{"b", "d", "a", "c", "e"} {
+		c.do("POST", "tag", resource("tag", "", map[string]interface{}{"label": label}, nil)).expect(http.StatusCreated)
+	}
+
+	// labels returns the labels of the listed tags in order
+	labels := func(r *testResponse) string {
+		var result strings.Builder
+		for _, record := range r.records() {
+			result.WriteString(fmt.Sprint(record.Attributes["label"]))
+		}
+		return result.String()
+	}
+
+	if got := labels(c.do("GET", "tag", nil).expect(http.StatusOK)); got != "bdace" {
+		t.Errorf("expected tags in insertion order, got %s", got)
+	}
+	if got := labels(c.do("GET", "tag?sort=-label", nil).expect(http.StatusOK)); got != "edcba" {
+		t.Errorf("expected tags sorted descending, got %s", got)
+	}
+	if got := labels(c.do("GET", "tag?filter[label]=a,c&sort=label", nil).expect(http.StatusOK)); got != "ac" {
+		t.Errorf("expected tags a and c, got %s", got)
+	}
+
+	page := c.do("GET", "tag?sort=label&page[number]=2&page[size]=2", nil).expect(http.StatusOK)
+	if got := labels(page); got != "cd" {
+		t.Errorf("expected the second page to hold c and d, got %s", got)
+	}
+	if total := fmt.Sprint(page.Links["total"]); total != "5" {
+		t.Errorf("expected a total of 5, got %s", total)
+	}
+	if got := labels(c.do("GET", "tag?sort=label&page[number]=4&page[size]=2", nil).expect(http.StatusOK)); got != "" {
+		t.Errorf("expected no tags after the last page, got %s", got)
+	}
+
+	c.do("GET", "tag?sort=unknown", nil).expect(http.StatusBadRequest)
+	c.do("GET", "tag?filter[unknown]=x", nil).expect(http.StatusBadRequest)
+}
+
+func TestMemoryResourceRelationships(t *testing.T) {
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient{t, api.Handler()}
+
+	// ids returns the ids of the resources listed at path
+	ids := func(path string) string {
+		t.Helper()
+		result := make([]string, 0)
+		for _, record := range c.do("GET", path, nil).expect(http.StatusOK).records() {
+			result = append(result, record.ID)
+		}
+		return strings.Join(result, ",")
+	}
+
+	c.do("POST", "user/u1/relationships/tag_id", identifiers("tag", "t3")).expect(http.StatusNoContent)
+	if got := ids("user/u1/tag_id"); got != "t1,t2,t3" {
+		t.Errorf("expected t1,t2,t3 after adding, got %s", got)
+	}
+	if got := ids("tag/t3/user_id"); got != "u1" {
+		t.Errorf("expected u1 on the other side, got %s", got)
+	}
+
+	c.do("DELETE", "user/u1/relationships/tag_id", identifiers("tag", "t1")).expect(http.StatusNoContent)
+	if got := ids("user/u1/tag_id"); got != "t2,t3" {
+		t.Errorf("expected t2,t3 after deleting, got %s", got)
+	}
+
+	c.do("PATCH", "user/u1/relationships/tag_id", identifiers("tag", "t1")).expect(http.StatusNoContent)
+	if got := ids("user/u1/tag_id"); got != "t1" {
+		t.Errorf("expected t1 after replacing, got %s", got)
+	}
+	c.do("PATCH", "user/u1/relationships/tag_id", identifiers("tag")).expect(http.StatusNoContent)
+	if got := ids("user/u1/tag_id"); got != "" {
+		t.Errorf("expected no tags after clearing, got %s", got)
+	}
+
+	// deleting a user removes its join rows and the posts' references
+	c.do("POST", "user/u1/relationships/tag_id", identifiers("tag", "t1")).expect(http.StatusNoContent)
+	c.do("DELETE", "user/u1", nil).expect(http.StatusNoContent)
+	if got := ids("tag/t1/user_id"); got != "" {
+		t.Errorf("expected no users of t1, got %s", got)
+	}
+}
+
+func TestMemoryResourceUpdateKeepsRelationsOnError(t *testing.T) {
+	api, store, resources := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient{t, api.Handler()}
+
+	// tags and posts are written before the comment fails, it is not stored
+	model := memoryTestModels()["user"]
+	update := api2go.NewApi2GoModelWithData("user", model.GetColumns(), 0, model.GetRelations(), map[string]interface{}{
+		"reference_id": "u1",
+		"name":         "changed",
+		"tag_id":       []map[string]interface{}{{"id": "t3"}},
+		"post_id":      []map[string]interface{}{{"id": "p2"}},
+		"comment_id":   []map[string]interface{}{{"id": "c1"}},
+	})
+	if _, err := resources["user"].Update(&update, api2go.Request{}); err == nil {
+		t.Fatal("expected an error for the unknown comment")
+	}
+
+	user := c.do("GET", "user/u1", nil).expect(http.StatusOK)
+	if user.attribute("name") != "ada" || user.attribute("version") != "1" {
+		t.Errorf("expected the user to be unchanged, got %v", user.record().Attributes)
+	}
+	if records := c.do("GET", "user/u1/tag_id", nil).expect(http.StatusOK).records(); len(records) != 2 {
+		t.Errorf("expected the tags t1 and t2 to stay, got %v", records)
+	}
+	if records := c.do("GET", "user/u1/post_id", nil).expect(http.StatusOK).records(); len(records) != 1 || records[0].ID != "p1" {
+		t.Errorf("expected the post p1 to stay, got %v", records)
+	}
+}
+
+func TestMemoryResourceConcurrentAccess(t *testing.T) {
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	handler := api.Handler()
+
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			c := testClient{t, handler}
+			tag := c.do("POST", "tag", resource("tag", "", map[string]interface{}{"label": fmt.Sprint(i)}, nil)).expect(http.StatusCreated).record().ID
+			c.do("POST", "user/u1/relationships/tag_id", identifiers("tag", tag)).expect(http.StatusNoContent)
+			c.do("PATCH", "user/u2", resource("user", "u2", map[string]interface{}{"age": i}, nil)).expect(http.StatusOK)
+			c.do("GET", "user?sort=-age", nil).expect(http.StatusOK)
+			c.do("GET", "user/u1/tag_id", nil).expect(http.StatusOK)
+		}(i)
+	}
+	wait.Wait()
+
+	c := testClient{t, handler}
+	if records := c.do("GET", "user/u1/tag_id", nil).expect(http.StatusOK).records(); len(records) != 10 {
+		t.Errorf("expected 10 tags of u1, got %d", len(records))
+	}
+	if version := c.do("GET", "user/u2", nil).expect(http.StatusOK).attribute("version"); version != "9" {
+		t.Errorf("expected version 9 after 8 updates, got %s", version)
+	}
+}
@@ -0,0 +1,235 @@
+package api2go
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestMetrics describes a finished request to a generated route
+type RequestMetrics struct {
+	Resource  string
+	Operation Operation
+	Status    int
+	Latency   time.Duration
+	// Size is the number of body bytes written
+	Size int
+}
+
+// An Observer is notified about every request to a generated route, except
+// OPTIONS requests
+type Observer interface {
+	ObserveRequest(metrics RequestMetrics)
+}
+
+// AddObserver adds an observer, e.g. a MetricsCollector
+func (api *API) AddObserver(observer Observer) {
+	api.observers = append(api.observers, observer)
+}
+
+// metricsWriter records status and size of a response
+type metricsWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (w *metricsWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *metricsWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += n
+	return n, err
+}
+
+// Unwrap allows http.ResponseController to reach the original writer
+func (w *metricsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (api *API) observe(res *resource, operation Operation, w *metricsWriter, start time.Time) {
+	metrics := RequestMetrics{
+		Resource:  res.name,
+		Operation: operation,
+		Status:    w.status,
+		Latency:   time.Since(start),
+		Size:      w.size,
+	}
+	if metrics.Status == 0 {
+		metrics.Status = http.StatusOK
+	}
+	for _, observer := range api.observers {
+		observer.ObserveRequest(metrics)
+	}
+}
+
+// DefaultLatencyBuckets are the upper bounds in seconds of the latency
+// histogram, the same as the Prometheus client defaults
+var DefaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// MetricsCollector is an Observer which serves request counts, response sizes
+// and latency histograms in the Prometheus text exposition format. Mount it
+// e.g. with http.Handle("/metrics", collector).
+type MetricsCollector struct {
+	mutex    sync.Mutex
+	buckets  []float64
+	requests map[requestSeries]uint64
+	bytes    map[requestSeries]uint64
+	latency  map[latencySeries]*histogram
+}
+
+type requestSeries struct {
+	resource  string
+	operation Operation
+	status    int
+}
+
+type latencySeries struct {
+	resource  string
+	operation Operation
+}
+
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// NewMetricsCollector returns a collector with the given latency buckets in
+// seconds, DefaultLatencyBuckets if none are given
+func NewMetricsCollector(buckets ...float64) *MetricsCollector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &MetricsCollector{
+		buckets:  buckets,
+		requests: make(map[requestSeries]uint64),
+		bytes:    make(map[requestSeries]uint64),
+		latency:  make(map[latencySeries]*histogram),
+	}
+}
+
+// ObserveRequest implements Observer
+func (m *MetricsCollector) ObserveRequest(metrics RequestMetrics) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	series := requestSeries{metrics.Resource, metrics.Operation, metrics.Status}
+	m.requests[series]++
+	m.bytes[series] += uint64(metrics.Size)
+
+	key := latencySeries{metrics.Resource, metrics.Operation}
+	h, ok := m.latency[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[key] = h
+	}
+	seconds := metrics.Latency.Seconds()
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// ServeHTTP writes all metrics in the Prometheus text format
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes all metrics in the Prometheus text format
+func (m *MetricsCollector) WriteTo(w io.Writer) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var b strings.Builder
+
+	requests := make([]requestSeries, 0, len(m.requests))
+	for series := range m.requests {
+		requests = append(requests, series)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.resource != b.resource {
+			return a.resource < b.resource
+		}
+		if a.operation != b.operation {
+			return a.operation < b.operation
+		}
+		return a.status < b.status
+	})
+
+	b.WriteString("# HELP api2go_requests_total Requests to generated routes.\n")
+	b.WriteString("# TYPE api2go_requests_total counter\n")
+	for _, series := range requests {
+		fmt.Fprintf(&b, "api2go_requests_total{%s} %d\n", series.labels(), m.requests[series])
+	}
+
+	b.WriteString("# HELP api2go_response_bytes_total Body bytes written by generated routes.\n")
+	b.WriteString("# TYPE api2go_response_bytes_total counter\n")
+	for _, series := range requests {
+		fmt.Fprintf(&b, "api2go_response_bytes_total{%s} %d\n", series.labels(), m.bytes[series])
+	}
+
+	latencies := make([]latencySeries, 0, len(m.latency))
+	for series := range m.latency {
+		latencies = append(latencies, series)
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		a, b := latencies[i], latencies[j]
+		if a.resource != b.resource {
+			return a.resource < b.resource
+		}
+		return a.operation < b.operation
+	})
+
+	b.WriteString("# HELP api2go_request_duration_seconds Latency of generated routes.\n")
+	b.WriteString("# TYPE api2go_request_duration_seconds histogram\n")
+	for _, series := range latencies {
+		h := m.latency[series]
+		labels := series.labels()
+		for i, bound := range m.buckets {
+			fmt.Fprintf(&b, "api2go_request_duration_seconds_bucket{%s,le=%q} %d\n",
+				labels, strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+		}
+		fmt.Fprintf(&b, "api2go_request_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels, h.count)
+		fmt.Fprintf(&b, "api2go_request_duration_seconds_sum{%s} %s\n", labels, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(&b, "api2go_request_duration_seconds_count{%s} %d\n", labels, h.count)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func (s requestSeries) labels() string {
+	return fmt.Sprintf(`resource="%s",operation="%s",status="%d"`,
+		escapeLabel(s.resource), escapeLabel(string(s.operation)), s.status)
+}
+
+func (s latencySeries) labels() string {
+	return fmt.Sprintf(`resource="%s",operation="%s"`, escapeLabel(s.resource), escapeLabel(string(s.operation)))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
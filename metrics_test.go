@@ -0,0 +1,89 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	api2go "github.com/artpar/api2go/v2"
+)
+
+// exposition returns the text served by collector
+func exposition(t *testing.T, collector *api2go.MetricsCollector) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	collector.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+	return w.Body.String()
+}
+
+// expectLines fails for every line which is not in text
+func expectLines(t *testing.T, text string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains("\n"+text, "\n"+line+"\n") {
+			t.Errorf("expected the line %q in\n%s", line, text)
+		}
+	}
+}
+
+func TestMetricsCollectorHandler(t *testing.T) {
+	api, _, _ := memoryTestAPI(t)
+	collector := api2go.NewMetricsCollector(60, 120)
+	api.AddObserver(collector)
+	c := testClient{t, api.Handler()}
+
+	id := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada"}, nil)).expect(http.StatusCreated).record().ID
+	c.do("GET", "user/"+id, nil).expect(http.StatusOK)
+	c.do("GET", "user/"+id, nil).expect(http.StatusOK)
+	c.do("GET", "user/unknown", nil).expect(http.StatusNotFound)
+	c.do("GET", "tag", nil).expect(http.StatusOK)
+
+	text := exposition(t, collector)
+	expectLines(t, text,
+		"# TYPE api2go_requests_total counter",
+		`api2go_requests_total{resource="user",operation="create",status="201"} 1`,
+		`api2go_requests_total{resource="user",operation="findOne",status="200"} 2`,
+		`api2go_requests_total{resource="user",operation="findOne",status="404"} 1`,
+		`api2go_requests_total{resource="tag",operation="findAll",status="200"} 1`,
+		"# TYPE api2go_response_bytes_total counter",
+		"# TYPE api2go_request_duration_seconds histogram",
+		`api2go_request_duration_seconds_bucket{resource="user",operation="findOne",le="60"} 3`,
+		`api2go_request_duration_seconds_bucket{resource="user",operation="findOne",le="120"} 3`,
+		`api2go_request_duration_seconds_bucket{resource="user",operation="findOne",le="+Inf"} 3`,
+		`api2go_request_duration_seconds_count{resource="user",operation="findOne"} 3`,
+		`api2go_request_duration_seconds_count{resource="user",operation="create"} 1`,
+	)
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, `api2go_response_bytes_total{resource="user",operation="findOne",status="200"}`) && strings.HasSuffix(line, " 0") {
+			t.Errorf("expected the body bytes to be counted, got %s", line)
+		}
+	}
+}
+
+func TestMetricsCollectorHistogram(t *testing.T) {
+	collector := api2go.NewMetricsCollector(1, 0.5)
+	for _, latency := range []time.Duration{200 * time.Millisecond, 700 * time.Millisecond, 3 * time.Second} {
+		collector.ObserveRequest(api2go.RequestMetrics{
+			Resource:  `a "b"`,
+			Operation: api2go.OperationFindAll,
+			Status:    http.StatusOK,
+			Latency:   latency,
+			Size:      10,
+		})
+	}
+
+	expectLines(t, exposition(t, collector),
+		`api2go_requests_total{resource="a \"b\"",operation="findAll",status="200"} 3`,
+		`api2go_response_bytes_total{resource="a \"b\"",operation="findAll",status="200"} 30`,
+		`api2go_request_duration_seconds_bucket{resource="a \"b\"",operation="findAll",le="0.5"} 1`,
+		`api2go_request_duration_seconds_bucket{resource="a \"b\"",operation="findAll",le="1"} 2`,
+		`api2go_request_duration_seconds_bucket{resource="a \"b\"",operation="findAll",le="+Inf"} 3`,
+		`api2go_request_duration_seconds_sum{resource="a \"b\"",operation="findAll"} 3.9`,
+		`api2go_request_duration_seconds_count{resource="a \"b\"",operation="findAll"} 3`,
+	)
+}
@@ -0,0 +1,50 @@
+package api2go_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+func TestNamingStrategyLeavesOutInternalAttributes(t *testing.T) {
+	models := memoryTestModels()
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPI("v1")
+	api.SetNamingStrategy(jsonapi.NamingKebabCase)
+	for _, name := range []string{"user", "tag", "post"} {
+		api.AddResource(models[name], store.Resource(models[name]))
+	}
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+
+	c := testClient{t, api.Handler()}
+	record := c.do("GET", "user/u1", nil).expect(http.StatusOK).record()
+	for _, name := range []string{"type", "reference-id", "__type"} {
+		if _, ok := record.Attributes[name]; ok {
+			t.Errorf("expected no attribute %s, got %v", name, record.Attributes)
+		}
+	}
+	if record.ID != "u1" || record.Attributes["name"] == nil {
+		t.Errorf("unexpected record %+v", record)
+	}
+}
+
+func TestNamingStrategyRefusesReservedMembers(t *testing.T) {
+	columns := []api2go.ColumnInfo{
+		{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+		{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+		{ColumnName: "Type", DataType: "varchar(20)"},
+	}
+	model := api2go.NewApi2GoModel("item", columns, 0, nil)
+	api := api2go.NewAPI("v1")
+	api.SetNamingStrategy(jsonapi.NamingSnakeCase)
+
+	err := api.TryAddResource(model, api2go.NewMemoryStore().Resource(model))
+	if err == nil || !strings.Contains(err.Error(), "member Type would be named type") {
+		t.Errorf("expected an error for the column Type, got %v", err)
+	}
+}
@@ -0,0 +1,22 @@
+package api2go
+
+// Operation names what a generated route does with a resource. It is used to
+// configure per-operation behaviour, e.g. rate limits.
+type Operation string
+
+// The operations of the generated routes
+const (
+	OperationFindAll             Operation = "findAll"
+	OperationFindOne             Operation = "findOne"
+	OperationCreate              Operation = "create"
+	OperationUpdate              Operation = "update"
+	OperationDelete              Operation = "delete"
+	OperationFindRelated         Operation = "findRelated"
+	OperationReadRelationship    Operation = "readRelationship"
+	OperationReplaceRelationship Operation = "replaceRelationship"
+	OperationAddToMany           Operation = "addToMany"
+	OperationDeleteFromMany      Operation = "deleteFromMany"
+	// OperationRootDocument serves the root document, its routes have no
+	// resource, see EnableRootDocument
+	OperationRootDocument Operation = "rootDocument"
+)
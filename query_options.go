@@ -0,0 +1,136 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type sortField struct {
+	name       string
+	descending bool
+}
+
+type filterField struct {
+	name   string
+	values []string
+}
+
+// listOptions holds the parsed `sort`, `filter[...]` and `page[...]` query
+// parameters of a FindAll or PaginatedFindAll request
+type listOptions struct {
+	sort    []sortField
+	filters []filterField
+	limit   uint64
+	offset  uint64
+}
+
+// parseListOptions reads sorting, filtering and pagination from req.
+// `known` reports whether a name may be used to sort or filter by, unknown
+// names are answered with a 400 error pointing to the offending parameter.
+func parseListOptions(req Request, known func(string) bool) (listOptions, error) {
+	var options listOptions
+
+	for _, name := range req.QueryParams["sort"] {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		field := sortField{name: name}
+		if strings.HasPrefix(name, "-") {
+			field = sortField{name: name[1:], descending: true}
+		}
+		if !known(field.name) {
+			return options, newQueryParamError(codeInvalidSortParam, "sort",
+				fmt.Sprintf(`Cannot sort by "%s"`, field.name))
+		}
+		options.sort = append(options.sort, field)
+	}
+
+	filterKeys := make([]string, 0)
+	for key := range req.QueryParams {
+		if queryFilterRegex.MatchString(key) {
+			filterKeys = append(filterKeys, key)
+		}
+	}
+	sort.Strings(filterKeys)
+	for _, key := range filterKeys {
+		name := queryFilterRegex.FindStringSubmatch(key)[1]
+		if !known(name) {
+			return options, newQueryParamError(codeInvalidFilterParam, key,
+				fmt.Sprintf(`Cannot filter by "%s"`, name))
+		}
+		options.filters = append(options.filters, filterField{name: name, values: req.QueryParams[key]})
+	}
+
+	parse := func(key string) (uint64, bool, error) {
+		value, ok := req.Pagination[key]
+		if !ok {
+			return 0, false, nil
+		}
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return 0, false, newQueryParamError(codeInvalidPageParam, "page["+key+"]",
+				fmt.Sprintf(`Invalid value "%s" for page[%s]`, value, key))
+		}
+		return parsed, true, nil
+	}
+
+	number, hasNumber, err := parse("number")
+	if err != nil {
+		return options, err
+	}
+	size, hasSize, err := parse("size")
+	if err != nil {
+		return options, err
+	}
+	offset, _, err := parse("offset")
+	if err != nil {
+		return options, err
+	}
+	limit, _, err := parse("limit")
+	if err != nil {
+		return options, err
+	}
+
+	if hasNumber || hasSize {
+		if !hasSize {
+			size = 10
+		}
+		if number < 1 {
+			number = 1
+		}
+		options.limit = size
+		options.offset = (number - 1) * size
+	} else {
+		options.limit = limit
+		options.offset = offset
+	}
+
+	return options, nil
+}
+
+func newQueryParamError(code, parameter, title string) HTTPError {
+	httpError := NewHTTPError(nil, title, http.StatusBadRequest)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(http.StatusBadRequest),
+		Code:   code,
+		Title:  title,
+		Source: &ErrorSource{Parameter: parameter},
+	})
+	return httpError
+}
+
+// relatedParent identifies the resource whose related resources are listed,
+// see RelatedFinder
+type relatedParent struct {
+	typeName string
+	id       string
+	relation string
+}
+
+func (p relatedParent) notFound(table string) HTTPError {
+	return NewHTTPError(nil, fmt.Sprintf("%s has no relationship %s to %s", p.typeName, p.relation, table), http.StatusNotFound)
+}
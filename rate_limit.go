@@ -0,0 +1,170 @@
+package api2go
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimit allows Requests requests per period Per and client. Unused
+// requests accumulate up to Requests, so short bursts are possible.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+	// Key identifies the client, e.g. by an API key stored in the context by
+	// a middleware. The default is the IP address of the request.
+	Key func(c APIContexter, r *http.Request) string
+}
+
+// A RateLimitStore keeps the buckets of all clients. Implement it to share
+// limits between several instances, e.g. in Redis.
+type RateLimitStore interface {
+	// Take takes one request from the bucket with the given key. If the
+	// bucket is empty, it returns false and the time until the next request
+	// is available.
+	Take(key string, limit RateLimit) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// SetRateLimit limits the given operations of a resource, all operations if
+// none are given. Operations can be limited separately by calling it again.
+func (api *API) SetRateLimit(resourceName string, limit RateLimit, operations ...Operation) {
+	if limit.Requests < 1 || limit.Per <= 0 {
+		panic("a rate limit needs at least one request per positive duration")
+	}
+	if api.rateLimits == nil {
+		api.rateLimits = make(map[string]map[Operation]RateLimit)
+	}
+	if api.rateLimits[resourceName] == nil {
+		api.rateLimits[resourceName] = make(map[Operation]RateLimit)
+	}
+	if len(operations) == 0 {
+		operations = []Operation{""}
+	}
+	for _, operation := range operations {
+		api.rateLimits[resourceName][operation] = limit
+	}
+	if api.rateLimitStore == nil {
+		api.rateLimitStore = NewMemoryRateLimitStore()
+	}
+}
+
+// SetRateLimitStore replaces the in-memory store used for rate limits
+func (api *API) SetRateLimitStore(store RateLimitStore) {
+	api.rateLimitStore = store
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// checkRateLimit returns a 429 error if the client exhausted the limit of the
+// operation. Failing stores do not block requests.
+func (api *API) checkRateLimit(c APIContexter, w http.ResponseWriter, r *http.Request, resourceName string, operation Operation) error {
+	limits, ok := api.rateLimits[resourceName]
+	if !ok || api.rateLimitStore == nil {
+		return nil
+	}
+	limit, ok := limits[operation]
+	scope := string(operation)
+	if !ok {
+		if limit, ok = limits[""]; !ok {
+			return nil
+		}
+		scope = "*"
+	}
+
+	client := clientIP(r)
+	if limit.Key != nil {
+		client = limit.Key(c, r)
+	}
+
+	allowed, retryAfter, err := api.rateLimitStore.Take(resourceName+":"+scope+":"+client, limit)
+	if err != nil {
+		api.errorHandling.logf("rate limit store: %v", err)
+		return nil
+	}
+	if allowed {
+		return nil
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	title := fmt.Sprintf("Too many requests, retry in %d seconds", seconds)
+	httpError := NewHTTPError(nil, title, http.StatusTooManyRequests)
+	httpError.Errors = append(httpError.Errors, Error{
+		Status: strconv.Itoa(http.StatusTooManyRequests),
+		Code:   codeRateLimited,
+		Title:  title,
+	})
+	return httpError
+}
+
+// MemoryRateLimitStore is a token bucket RateLimitStore for a single instance
+type MemoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	takes   int
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// NewMemoryRateLimitStore returns an empty store
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*tokenBucket)}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	rate := float64(b.limit.Requests) / b.limit.Per.Seconds()
+	b.tokens = math.Min(float64(b.limit.Requests), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+}
+
+// Take implements RateLimitStore
+func (s *MemoryRateLimitStore) Take(key string, limit RateLimit) (bool, time.Duration, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	s.takes++
+	if s.takes%1024 == 0 {
+		// forget clients whose buckets are full again
+		for k, bucket := range s.buckets {
+			bucket.refill(now)
+			if bucket.tokens >= float64(bucket.limit.Requests) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(limit.Requests), last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.limit = limit
+	bucket.refill(now)
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0, nil
+	}
+
+	rate := float64(limit.Requests) / limit.Per.Seconds()
+	return false, time.Duration((1 - bucket.tokens) / rate * float64(time.Second)), nil
+}
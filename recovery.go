@@ -0,0 +1,76 @@
+package api2go
+
+import (
+	"context"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// requestIDHeader carries the id of a request, it is taken from the request
+// if the client or a proxy sent one
+const requestIDHeader = "X-Request-ID"
+
+// A PanicHandler is called with the recovered value and the stack trace if a
+// generated route handler panics
+type PanicHandler func(r *http.Request, requestID string, recovered interface{}, stack []byte)
+
+// SetPanicHandler sets the hook for panics in route handlers. By default they
+// are written to the logger of the api.
+func (api *API) SetPanicHandler(handler PanicHandler) {
+	api.panicHandler = handler
+}
+
+type requestIDKey struct{}
+
+// RequestID returns the id which the access log assigned to the request of
+// ctx, or an empty string
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func requestID(r *http.Request) string {
+	if id := RequestID(r.Context()); id != "" {
+		return id
+	}
+	if id := r.Header.Get(requestIDHeader); id != "" {
+		return id
+	}
+	return uuid.NewString()
+}
+
+// withRequestID assigns an id to the request and announces it in the response
+func withRequestID(w http.ResponseWriter, r *http.Request) *http.Request {
+	id := requestID(r)
+	w.Header().Set(requestIDHeader, id)
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id))
+}
+
+// recoverPanic reports a recovered panic and answers with a 500 error
+// document, which carries the request id but nothing of the panic itself
+func (api *API) recoverPanic(w http.ResponseWriter, r *http.Request, recovered interface{}) {
+	if recovered == http.ErrAbortHandler {
+		panic(recovered)
+	}
+
+	id := requestID(r)
+	stack := debug.Stack()
+	if api.panicHandler != nil {
+		api.panicHandler(r, id, recovered, stack)
+	} else {
+		api.errorHandling.logf("panic in %s %s (request %s): %v\n%s", r.Method, r.URL.Path, id, recovered, stack)
+	}
+
+	title := http.StatusText(http.StatusInternalServerError)
+	httpError := NewHTTPError(nil, title, http.StatusInternalServerError)
+	httpError.Errors = append(httpError.Errors, Error{
+		ID:     id,
+		Status: strconv.Itoa(http.StatusInternalServerError),
+		Title:  title,
+	})
+	w.Header().Set(requestIDHeader, id)
+	writeResult(w, []byte(marshalHTTPError(httpError)), http.StatusInternalServerError, api.ContentType)
+}
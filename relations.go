@@ -0,0 +1,203 @@
+package api2go
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// RelationKind is the kind of a TableRelation
+type RelationKind string
+
+// The supported relation kinds
+const (
+	RelationBelongsTo  RelationKind = "belongs_to"
+	RelationHasOne     RelationKind = "has_one"
+	RelationHasMany    RelationKind = "has_many"
+	RelationManyToMany RelationKind = "has_many_and_belongs_to_many"
+)
+
+// Valid reports whether k is one of the supported kinds
+func (k RelationKind) Valid() bool {
+	switch k {
+	case RelationBelongsTo, RelationHasOne, RelationHasMany, RelationManyToMany:
+		return true
+	}
+	return false
+}
+
+// ToMany reports whether the subject has many objects
+func (k RelationKind) ToMany() bool {
+	return k == RelationHasMany || k == RelationManyToMany
+}
+
+// NewBelongsTo returns a relation in which subject belongs to one object
+func NewBelongsTo(subject, object string) TableRelation {
+	return NewTableRelation(subject, RelationBelongsTo, object)
+}
+
+// NewHasOne returns a relation in which subject has one object
+func NewHasOne(subject, object string) TableRelation {
+	return NewTableRelation(subject, RelationHasOne, object)
+}
+
+// NewHasMany returns a relation in which subject has many objects
+func NewHasMany(subject, object string) TableRelation {
+	return NewTableRelation(subject, RelationHasMany, object)
+}
+
+// NewManyToMany returns a relation in which subjects and objects have many of
+// each other
+func NewManyToMany(subject, object string) TableRelation {
+	return NewTableRelation(subject, RelationManyToMany, object)
+}
+
+// RelationError describes a misconfigured relation of a resource
+type RelationError struct {
+	Resource string
+	Relation string
+	Problem  string
+}
+
+func (e *RelationError) Error() string {
+	return fmt.Sprintf("relation %s of resource %s: %s", e.Relation, e.Resource, e.Problem)
+}
+
+// ValidateRelations checks the relations of all resources. Relations are
+// checked when a resource is added, but whether every related type has
+// been registered can only be answered once all resources are added.
+func (api *API) ValidateRelations() error {
+	resources := api.resourceList()
+	var errs []*RelationError
+	for _, res := range resources {
+		errs = append(errs, relationErrors(res.name, res.prototype, resources, true)...)
+	}
+	return joinRelationErrors(errs)
+}
+
+// validateGraph checks the relations of prototypes, which are about to be
+// registered together, and those of the registered resources against all of
+// them. Only the targets of prototypes must be registered, as the resources
+// registered before may point at types which are added later.
+func (api *API) validateGraph(prototypes ...jsonapi.MarshalIdentifier) error {
+	registered := api.resourceList()
+	resources := append([]resource{}, registered...)
+	for _, prototype := range prototypes {
+		resources = append(resources, resource{name: resourceName(prototype), prototype: prototype})
+	}
+
+	var errs []*RelationError
+	for i, res := range resources {
+		errs = append(errs, relationErrors(res.name, res.prototype, resources, i >= len(registered))...)
+	}
+	return joinRelationErrors(errs)
+}
+
+// checkRelations returns the *RelationError of every relation of prototype
+// which is invalid or conflicts with the registered resources
+func (api *API) checkRelations(name string, prototype jsonapi.MarshalIdentifier) error {
+	return joinRelationErrors(relationErrors(name, prototype, api.resourceList(), false))
+}
+
+// joinRelationErrors returns nil for no errors, the error itself for one
+// and the joined errors otherwise
+func joinRelationErrors(relationErrors []*RelationError) error {
+	switch len(relationErrors) {
+	case 0:
+		return nil
+	case 1:
+		return relationErrors[0]
+	}
+	errs := make([]error, len(relationErrors))
+	for i, relationError := range relationErrors {
+		errs[i] = relationError
+	}
+	return errors.Join(errs...)
+}
+
+// modelRelations returns the table relations of an Api2GoModel prototype
+func modelRelations(prototype interface{}) ([]TableRelation, bool) {
+	switch model := prototype.(type) {
+	case Api2GoModel:
+		return model.relations, true
+	case *Api2GoModel:
+		return model.relations, true
+	}
+	return nil, false
+}
+
+func relationErrors(name string, prototype jsonapi.MarshalIdentifier, resources []resource, requireTargets bool) []*RelationError {
+	var errs []*RelationError
+	problem := func(relation, format string, args ...interface{}) {
+		errs = append(errs, &RelationError{Resource: name, Relation: relation, Problem: fmt.Sprintf(format, args...)})
+	}
+
+	relations, isModel := modelRelations(prototype)
+	for _, relation := range relations {
+		label := relation.GetSubjectName() + "/" + relation.GetObjectName()
+		if !relation.Relation.Valid() {
+			problem(label, "unknown kind %q", relation.Relation)
+		}
+		if relation.Subject != name && relation.Object != name {
+			problem(label, "it relates %s to %s and does not involve %s", relation.Subject, relation.Object, name)
+		}
+	}
+
+	registered := map[string]resource{}
+	for _, res := range resources {
+		registered[res.name] = res
+	}
+
+	if references, ok := prototype.(jsonapi.MarshalReferences); ok {
+		seen := map[string]bool{}
+		for _, reference := range references.GetReferences() {
+			if reference.Name == "" || reference.Type == "" {
+				if !isModel {
+					problem(reference.Name, "references need a name and a type")
+				}
+				continue
+			}
+			if seen[reference.Name] {
+				problem(reference.Name, "the name is used by more than one relation")
+			}
+			seen[reference.Name] = true
+			if _, ok := registered[reference.Type]; requireTargets && !ok && reference.Type != name {
+				problem(reference.Name, "it points at %s, which is not registered", reference.Type)
+			}
+		}
+	}
+
+	// both sides of a relation must declare it the same way
+	for _, relation := range relations {
+		other := relation.Object
+		if other == name {
+			other = relation.Subject
+		}
+		res, ok := registered[other]
+		if !ok || other == name {
+			continue
+		}
+		otherRelations, _ := modelRelations(res.prototype)
+		for _, otherRelation := range otherRelations {
+			if otherRelation.Subject != relation.Subject || otherRelation.Object != relation.Object {
+				continue
+			}
+			sameObjectName := otherRelation.GetObjectName() == relation.GetObjectName()
+			sameSubjectName := otherRelation.GetSubjectName() == relation.GetSubjectName()
+			if !sameObjectName && !sameSubjectName {
+				// a different relation between the same resources
+				continue
+			}
+			label := relation.GetSubjectName() + "/" + relation.GetObjectName()
+			if !sameObjectName || !sameSubjectName {
+				problem(label, "resource %s declares the inverse as %s/%s",
+					other, otherRelation.GetSubjectName(), otherRelation.GetObjectName())
+			} else if otherRelation.Relation != relation.Relation {
+				problem(label, "it is %s here and %s in resource %s", relation.Relation, otherRelation.Relation, other)
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,111 @@
+package api2go_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// unnamedReferences is a struct resource with a reference lacking its name
+type unnamedReferences struct{}
+
+func (unnamedReferences) GetID() string { return "" }
+
+func (unnamedReferences) GetAttributes() map[string]interface{} { return nil }
+
+func (unnamedReferences) GetReferences() []jsonapi.Reference {
+	return []jsonapi.Reference{{Type: "tag"}}
+}
+
+func relationModel(name string, relations ...api2go.TableRelation) api2go.Api2GoModel {
+	columns := []api2go.ColumnInfo{
+		{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+		{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+	}
+	return api2go.NewApi2GoModel(name, columns, 0, relations)
+}
+
+func TestRelationErrors(t *testing.T) {
+	hasMany := api2go.NewHasMany("user", "tag")
+
+	tests := []struct {
+		name       string
+		registered []api2go.Api2GoModel
+		prototype  jsonapi.MarshalIdentifier
+		want       string
+	}{
+		{"unknown kind", nil, relationModel("user", api2go.NewTableRelation("user", "owns", "tag")), `unknown kind "owns"`},
+		{"not involved", nil, relationModel("user", api2go.NewHasMany("post", "tag")), "does not involve user"},
+		{"missing name", nil, unnamedReferences{}, "references need a name and a type"},
+		{"duplicate name", nil, relationModel("user", hasMany, api2go.NewBelongsTo("user", "tag")), "used by more than one relation"},
+		{
+			"inverse with other names",
+			[]api2go.Api2GoModel{relationModel("tag", api2go.NewTableRelationWithNames("user", "owner_id", api2go.RelationHasMany, "tag", "tag_id"))},
+			relationModel("user", hasMany),
+			"resource tag declares the inverse as owner_id/tag_id",
+		},
+		{
+			"inverse of another kind",
+			[]api2go.Api2GoModel{relationModel("tag", hasMany)},
+			relationModel("user", api2go.NewManyToMany("user", "tag")),
+			"it is has_many_and_belongs_to_many here and has_many in resource tag",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := api2go.NewMemoryStore()
+			api := api2go.NewAPI("v1")
+			for _, model := range test.registered {
+				api.AddResource(model, store.Resource(model))
+			}
+
+			routes := len(api.Routes())
+			err := api.TryAddResource(test.prototype, store.Resource(relationModel("user")))
+			var relationError *api2go.RelationError
+			if !errors.As(err, &relationError) || !strings.Contains(err.Error(), test.want) {
+				t.Fatalf("expected a RelationError containing %q, got %v", test.want, err)
+			}
+			if len(api.Routes()) != routes {
+				t.Errorf("expected the resource not to be added, got %v", api.Routes())
+			}
+		})
+	}
+}
+
+func TestValidateRelations(t *testing.T) {
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPI("v1")
+	user := relationModel("user", api2go.NewHasMany("user", "tag"))
+	// the target of a relation may be added later
+	if err := api.TryAddResource(user, store.Resource(user)); err != nil {
+		t.Fatal(err)
+	}
+
+	err := api.ValidateRelations()
+	var relationError *api2go.RelationError
+	if !errors.As(err, &relationError) || relationError.Resource != "user" || relationError.Relation != "tag_id" {
+		t.Fatalf("expected a RelationError for the relation tag_id of user, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "it points at tag, which is not registered") {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	tag := relationModel("tag", api2go.NewHasMany("user", "tag"))
+	api.AddResource(tag, store.Resource(tag))
+	if err := api.ValidateRelations(); err != nil {
+		t.Errorf("expected the relations to be valid, got %v", err)
+	}
+}
+
+func TestRelationErrorsAreJoined(t *testing.T) {
+	api := api2go.NewAPI("v1")
+	user := relationModel("user", api2go.NewTableRelation("user", "owns", "tag"), api2go.NewHasMany("post", "tag"))
+	err := api.TryAddResource(user, api2go.NewMemoryStore().Resource(user))
+	if err == nil || !strings.Contains(err.Error(), "unknown kind") || !strings.Contains(err.Error(), "does not involve user") {
+		t.Errorf("expected both problems to be reported, got %v", err)
+	}
+}
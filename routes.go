@@ -0,0 +1,148 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+// Route describes a route generated for a resource
+type Route struct {
+	Method string
+	Path   string
+	// Resource is the name of the resource, empty for the root document
+	Resource string
+	// Relation and RelatedType are set for relationship and related routes
+	Relation    string
+	RelatedType string
+	// Operation is empty for OPTIONS routes
+	Operation Operation
+	// Paginated routes answer page parameters with pagination links
+	Paginated bool
+	// ToMany is set for routes of to-many relations
+	ToMany bool
+	// EditableToMany is set for relationship routes which allow adding and
+	// removing single members with POST and DELETE
+	EditableToMany bool
+}
+
+type routeEntry struct {
+	res       *resource
+	relation  *jsonapi.Reference
+	operation Operation
+	method    string
+	path      string
+}
+
+func isToMany(relation jsonapi.Reference) bool {
+	if relation.Relationship == jsonapi.DefaultRelationship {
+		return jsonapi.Pluralize(relation.Name) == relation.Name
+	}
+	return relation.Relationship == jsonapi.ToManyRelationship
+}
+
+// Routes returns all generated routes in the order they were registered
+func (api *API) Routes() []Route {
+	api.resourceMutex.RLock()
+	entries := api.routes
+	api.resourceMutex.RUnlock()
+
+	editable := map[string]bool{}
+	for _, entry := range entries {
+		if entry.operation == OperationAddToMany {
+			editable[entry.res.name+"/"+entry.relation.Name] = true
+		}
+	}
+
+	routes := make([]Route, 0, len(entries))
+	for _, entry := range entries {
+		route := Route{
+			Method:    entry.method,
+			Path:      entry.path,
+			Resource:  entry.res.name,
+			Operation: entry.operation,
+		}
+		if entry.relation != nil {
+			route.Relation = entry.relation.Name
+			route.RelatedType = entry.relation.Type
+			route.ToMany = isToMany(*entry.relation)
+			route.EditableToMany = editable[entry.res.name+"/"+entry.relation.Name]
+		}
+
+		switch entry.operation {
+		case OperationFindAll:
+			_, route.Paginated = entry.res.source.(PaginatedFindAll)
+		case OperationFindRelated, OperationReadRelationship:
+			if related, ok := api.findResource(entry.relation.Type); ok {
+				route.Paginated = relatedPaginated(related.source, entry.operation)
+			}
+		}
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+// relatedPaginated tells if the related resource pages the results of a
+// findRelated or readRelationship route. The checks follow handleLinked and
+// handleReadRelation: a RelatedFinder answers findRelated without pages.
+func relatedPaginated(source interface{}, operation Operation) bool {
+	if _, ok := source.(PaginatedRelatedFinder); ok {
+		return true
+	}
+	if operation != OperationFindRelated {
+		return false
+	}
+	if _, ok := source.(RelatedFinder); ok {
+		return false
+	}
+	_, ok := source.(PaginatedFindAll)
+	return ok
+}
+
+// EnableRootDocument adds GET {prefix}/, which answers with a document that
+// links every resource collection, so clients can discover the api
+func (api *API) EnableRootDocument() {
+	path := "/"
+	if prefix := strings.Trim(api.info.prefix, "/"); prefix != "" {
+		path = "/" + prefix + "/"
+	}
+	// the root document belongs to no resource, the empty name picks the
+	// settings of the api, e.g. its deprecation
+	root := &resource{api: api}
+	api.handle(root, nil, OperationRootDocument, http.MethodGet, path, func(c APIContexter, w http.ResponseWriter, r *http.Request, _ map[string]string) error {
+		info := api.info
+		if resolver, ok := api.info.resolver.(RequestAwareURLResolver); ok {
+			resolver.SetRequest(*r)
+			info = information{prefix: api.info.prefix, resolver: resolver, naming: api.info.naming}
+		}
+
+		base := info.GetBaseURL()
+		if prefix := strings.Trim(info.GetPrefix(), "/"); prefix != "" {
+			base = fmt.Sprintf("%s/%s", base, prefix)
+		}
+
+		links := jsonapi.Links{}
+		resources := api.resourceList()
+		names := make([]string, 0, len(resources))
+		for _, res := range resources {
+			name := info.naming.Name(res.name)
+			links[name] = jsonapi.Link{Href: base + "/" + name}
+			names = append(names, name)
+		}
+		links["self"] = jsonapi.Link{Href: base + "/"}
+
+		meta := map[string]interface{}{"resources": names}
+		if api.version != "" {
+			meta["version"] = api.version
+		}
+
+		result, err := jsonLib.Marshal(map[string]interface{}{"links": links, "meta": meta})
+		if err != nil {
+			return err
+		}
+		writeResult(w, result, http.StatusOK, api.ContentType)
+		return nil
+	})
+}
@@ -0,0 +1,44 @@
+package api2go
+
+import "testing"
+
+type pagedSource struct{}
+
+func (pagedSource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	return 0, nil, nil
+}
+
+type relatedSource struct{ pagedSource }
+
+func (relatedSource) FindRelated(parentType, parentID, relationName string, req Request) (Responder, error) {
+	return nil, nil
+}
+
+type pagedRelatedSource struct{ relatedSource }
+
+func (pagedRelatedSource) PaginatedFindRelated(parentType, parentID, relationName string, req Request) (uint, Responder, error) {
+	return 0, nil, nil
+}
+
+func TestRelatedPaginated(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  interface{}
+		related bool
+		read    bool
+	}{
+		{"neither", struct{}{}, false, false},
+		{"PaginatedFindAll", pagedSource{}, true, false},
+		{"RelatedFinder before PaginatedFindAll", relatedSource{}, false, false},
+		{"PaginatedRelatedFinder", pagedRelatedSource{}, true, true},
+	}
+
+	for _, test := range tests {
+		if got := relatedPaginated(test.source, OperationFindRelated); got != test.related {
+			t.Errorf("%s: expected findRelated to be paginated: %v", test.name, test.related)
+		}
+		if got := relatedPaginated(test.source, OperationReadRelationship); got != test.read {
+			t.Errorf("%s: expected readRelationship to be paginated: %v", test.name, test.read)
+		}
+	}
+}
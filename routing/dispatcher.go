@@ -0,0 +1,173 @@
+package routing
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// Dispatcher is a Routeable which keeps its own route table and builds a new
+// router from it whenever the table changes. The new router is swapped in
+// atomically, so routes can be removed and replaced while requests are
+// served. Any router can be used through the newRouter factory.
+type Dispatcher struct {
+	newRouter func() Routeable
+	mutex     sync.Mutex
+	routes    []dispatchRoute
+	// staged collects the changes of a batch
+	staged  []dispatchRoute
+	commits []func()
+	batches int
+	current atomic.Value
+}
+
+type dispatchRoute struct {
+	group    string
+	protocol string
+	route    string
+	handler  HandlerFunc
+}
+
+// NewDispatcher returns an empty dispatcher which builds its routers with
+// newRouter
+func NewDispatcher(newRouter func() Routeable) *Dispatcher {
+	d := &Dispatcher{newRouter: newRouter}
+	d.current.Store(currentHandler{newRouter().Handler()})
+	return d
+}
+
+// Handler returns the dispatcher itself, which forwards every request to the
+// current router
+func (d *Dispatcher) Handler() http.Handler {
+	return d
+}
+
+// ServeHTTP implements http.Handler
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.current.Load().(currentHandler).ServeHTTP(w, r)
+}
+
+// Handle implements Routeable, the route belongs to no group
+func (d *Dispatcher) Handle(protocol, route string, handler HandlerFunc) {
+	d.HandleGroup("", protocol, route, handler)
+}
+
+// HandleGroup adds a route to a group, which can be removed as a whole
+func (d *Dispatcher) HandleGroup(group, protocol, route string, handler HandlerFunc) {
+	d.change(func(routes []dispatchRoute) []dispatchRoute {
+		return append(routes, dispatchRoute{group: group, protocol: protocol, route: route, handler: handler})
+	})
+}
+
+// RemoveGroup removes all routes of a group
+func (d *Dispatcher) RemoveGroup(group string) {
+	d.change(func(routes []dispatchRoute) []dispatchRoute {
+		kept := routes[:0:0]
+		for _, route := range routes {
+			if route.group != group {
+				kept = append(kept, route)
+			}
+		}
+		return kept
+	})
+}
+
+// Batch applies all changes made by fn at once, requests never see a part
+// of them. If fn fails or the changed routes conflict, no change is applied
+// and the error is returned, the same if fn panics. A batch in a batch is
+// applied with the outer one.
+func (d *Dispatcher) Batch(fn func() error) (err error) {
+	d.mutex.Lock()
+	if d.batches == 0 {
+		d.staged = append([]dispatchRoute(nil), d.routes...)
+		d.commits = nil
+	}
+	d.batches++
+	d.mutex.Unlock()
+
+	committed := false
+	defer func() {
+		d.mutex.Lock()
+		defer d.mutex.Unlock()
+		d.batches--
+		if d.batches > 0 {
+			return
+		}
+		staged, commits := d.staged, d.commits
+		d.staged, d.commits = nil, nil
+		if !committed {
+			return
+		}
+		router, buildErr := d.build(staged)
+		if buildErr != nil {
+			err = buildErr
+			return
+		}
+		for _, commit := range commits {
+			commit()
+		}
+		d.routes = staged
+		d.current.Store(currentHandler{router.Handler()})
+	}()
+
+	if err := fn(); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// OnCommit registers fn to be called when the current batch is applied,
+// after its routes were checked and right before they are served. Outside of
+// a batch fn is called at once.
+func (d *Dispatcher) OnCommit(fn func()) {
+	d.mutex.Lock()
+	if d.batches > 0 {
+		d.commits = append(d.commits, fn)
+		d.mutex.Unlock()
+		return
+	}
+	d.mutex.Unlock()
+	fn()
+}
+
+// change applies fn to the staged routes in a batch, otherwise it swaps in
+// a router with the changed routes right away. Outside of a batch conflicting
+// routes panic like they do in the routers.
+func (d *Dispatcher) change(fn func([]dispatchRoute) []dispatchRoute) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.batches > 0 {
+		d.staged = fn(d.staged)
+		return
+	}
+	routes := fn(append([]dispatchRoute(nil), d.routes...))
+	router, err := d.build(routes)
+	if err != nil {
+		panic(err)
+	}
+	d.routes = routes
+	d.current.Store(currentHandler{router.Handler()})
+}
+
+// build returns a new router for routes. Routers panic on conflicting
+// routes, which build returns as error.
+func (d *Dispatcher) build(routes []dispatchRoute) (router Routeable, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("invalid routes: %v", recovered)
+		}
+	}()
+	router = d.newRouter()
+	for _, route := range routes {
+		router.Handle(route.protocol, route.route, route.handler)
+	}
+	return router, nil
+}
+
+// currentHandler gives atomic.Value the same type for every router
+type currentHandler struct {
+	http.Handler
+}
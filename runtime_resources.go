@@ -0,0 +1,207 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+	"github.com/artpar/api2go/v2/routing"
+)
+
+// RemoveResource removes a resource and all its routes while the api is
+// serving. It needs an api with a routing.Dispatcher, which all constructors
+// except NewAPIWithRouting use.
+func (api *API) RemoveResource(name string) error {
+	dispatcher, err := api.dispatcher()
+	if err != nil {
+		return err
+	}
+
+	api.changeMutex.Lock()
+	defer api.changeMutex.Unlock()
+
+	if _, ok := api.findResource(name); !ok {
+		return fmt.Errorf("there is no resource %s", name)
+	}
+	return api.batch(func() error {
+		dispatcher.RemoveGroup(api.resourceBaseURL(name))
+		api.dropResource(name, true)
+		return nil
+	})
+}
+
+// ReplaceResource registers a resource like AddResource, replacing a resource
+// with the same name. Requests see either all routes of the old or all routes
+// of the new resource. If the new routes conflict with others, the old
+// resource stays and the error is returned.
+func (api *API) ReplaceResource(prototype jsonapi.MarshalIdentifier, source interface{}) error {
+	dispatcher, err := api.dispatcher()
+	if err != nil {
+		return err
+	}
+
+	api.changeMutex.Lock()
+	defer api.changeMutex.Unlock()
+
+	if err := api.memberNameError(prototype); err != nil {
+		return err
+	}
+	name := resourceName(prototype)
+	if err := api.checkRelations(name, prototype); err != nil {
+		return err
+	}
+	return api.batch(func() error {
+		dispatcher.RemoveGroup(api.resourceBaseURL(name))
+		api.dropResource(name, false)
+		api.addResource(prototype, source)
+		return nil
+	})
+}
+
+func (api *API) dispatcher() (*routing.Dispatcher, error) {
+	dispatcher, ok := api.router.(*routing.Dispatcher)
+	if !ok {
+		return nil, fmt.Errorf("resources can only be removed and replaced in an api with a routing.Dispatcher, not %T", api.router)
+	}
+	return dispatcher, nil
+}
+
+// resourceState holds the resources and routes changed in a batch until the
+// dispatcher serves them
+type resourceState struct {
+	resources []resource
+	routes    []routeEntry
+}
+
+// batch runs fn in a batch of the dispatcher, if the router is one, so the
+// routes of a resource are swapped in together. The changes of fn to the
+// resources and routes of the api are kept only if the batch is applied.
+func (api *API) batch(fn func() error) error {
+	dispatcher, ok := api.router.(*routing.Dispatcher)
+	if !ok || api.staged != nil {
+		return fn()
+	}
+
+	defer func() {
+		api.resourceMutex.Lock()
+		api.staged = nil
+		api.resourceMutex.Unlock()
+	}()
+	return dispatcher.Batch(func() error {
+		api.resourceMutex.Lock()
+		api.staged = &resourceState{
+			resources: append([]resource(nil), api.resources...),
+			routes:    append([]routeEntry(nil), api.routes...),
+		}
+		api.resourceMutex.Unlock()
+
+		dispatcher.OnCommit(func() {
+			api.resourceMutex.Lock()
+			api.resources, api.routes = api.staged.resources, api.staged.routes
+			api.resourceMutex.Unlock()
+		})
+		return fn()
+	})
+}
+
+// state returns the resources and routes to change, the staged ones in a
+// batch. The caller must hold resourceMutex.
+func (api *API) state() (*[]resource, *[]routeEntry) {
+	if api.staged != nil {
+		return &api.staged.resources, &api.staged.routes
+	}
+	return &api.resources, &api.routes
+}
+
+// resourceBaseURL returns the collection path of a resource, which also
+// names the route group of the resource in a dispatcher
+func (api *API) resourceBaseURL(name string) string {
+	name = api.info.naming.Name(name)
+	prefix := strings.Trim(api.info.prefix, "/")
+	if prefix == "" {
+		return "/" + name
+	}
+	return "/" + prefix + "/" + name
+}
+
+// route registers a route of res, in its group if the router is a dispatcher
+func (api *API) route(res *resource, method, path string, handler routing.HandlerFunc) {
+	if dispatcher, ok := api.router.(*routing.Dispatcher); ok {
+		dispatcher.HandleGroup(api.resourceBaseURL(res.name), method, path, handler)
+		return
+	}
+	api.router.Handle(method, path, handler)
+}
+
+func (api *API) addRoute(entry routeEntry) {
+	api.resourceMutex.Lock()
+	defer api.resourceMutex.Unlock()
+
+	_, routes := api.state()
+	*routes = append(*routes, entry)
+}
+
+// putResource adds res or replaces the resource with the same name. The
+// slice is copied, so resources found before stay valid.
+func (api *API) putResource(res resource) {
+	api.resourceMutex.Lock()
+	defer api.resourceMutex.Unlock()
+
+	current, _ := api.state()
+	resources := make([]resource, 0, len(*current)+1)
+	replaced := false
+	for _, existing := range *current {
+		if existing.name == res.name {
+			existing = res
+			replaced = true
+		}
+		resources = append(resources, existing)
+	}
+	if !replaced {
+		resources = append(resources, res)
+	}
+	*current = resources
+}
+
+// dropResource forgets the routes of a resource, and the resource itself if
+// withResource is set
+func (api *API) dropResource(name string, withResource bool) {
+	api.resourceMutex.Lock()
+	defer api.resourceMutex.Unlock()
+
+	currentResources, currentRoutes := api.state()
+	routes := make([]routeEntry, 0, len(*currentRoutes))
+	for _, entry := range *currentRoutes {
+		if entry.res.name != name {
+			routes = append(routes, entry)
+		}
+	}
+	*currentRoutes = routes
+
+	if !withResource {
+		return
+	}
+	resources := make([]resource, 0, len(*currentResources))
+	for _, existing := range *currentResources {
+		if existing.name != name {
+			resources = append(resources, existing)
+		}
+	}
+	*currentResources = resources
+}
+
+// resourceList returns the registered resources
+func (api *API) resourceList() []resource {
+	api.resourceMutex.RLock()
+	defer api.resourceMutex.RUnlock()
+	return api.resources
+}
+
+// newDefaultRouter returns the dispatcher used by the constructors, which
+// builds httprouters answering unknown methods with notAllowed
+func newDefaultRouter(prefix string, notAllowed http.Handler) routing.Routeable {
+	return routing.NewDispatcher(func() routing.Routeable {
+		return routing.NewHTTPRouter(prefix, notAllowed)
+	})
+}
@@ -0,0 +1,121 @@
+package api2go_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+	"github.com/artpar/api2go/v2/routing"
+)
+
+func TestRemoveAndReplaceResource(t *testing.T) {
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient{t, api.Handler()}
+
+	if err := api.RemoveResource("tag"); err != nil {
+		t.Fatal(err)
+	}
+	c.do("GET", "tag/t1", nil).expect(http.StatusNotFound)
+	c.do("GET", "user/u1", nil).expect(http.StatusOK)
+	for _, route := range api.Routes() {
+		if route.Resource == "tag" {
+			t.Errorf("expected the route %s %s to be removed", route.Method, route.Path)
+		}
+	}
+	if err := api.RemoveResource("tag"); err == nil {
+		t.Error("expected an error removing a resource twice")
+	}
+
+	models := memoryTestModels()
+	if err := api.ReplaceResource(models["user"], store.Resource(models["user"])); err != nil {
+		t.Fatal(err)
+	}
+	c.do("GET", "user/u1", nil).expect(http.StatusOK)
+}
+
+func TestReplaceResourceConflict(t *testing.T) {
+	models := memoryTestModels()
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPI("v1")
+	api.AddResource(models["tag"], store.Resource(models["tag"]))
+	// the wildcard :name conflicts with the :id of the user routes
+	api.Router().Handle("GET", "/v1/user/:name/avatar", func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	if err := api.ReplaceResource(models["user"], store.Resource(models["user"])); err == nil {
+		t.Fatal("expected an error for conflicting routes")
+	}
+	for _, route := range api.Routes() {
+		if route.Resource == "user" {
+			t.Errorf("expected no route of the conflicting resource, got %s %s", route.Method, route.Path)
+		}
+	}
+
+	c := testClient{t, api.Handler()}
+	c.do("GET", "tag", nil).expect(http.StatusOK)
+	c.do("GET", "user/ada/avatar", nil).expect(http.StatusTeapot)
+}
+
+func TestRuntimeResourcesNeedADispatcher(t *testing.T) {
+	models := memoryTestModels()
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPIWithRouting("v1", api2go.NewStaticResolver(""), routing.NewHTTPRouter("v1", http.NotFoundHandler()))
+	api.AddResource(models["tag"], store.Resource(models["tag"]))
+
+	if err := api.RemoveResource("tag"); err == nil {
+		t.Error("expected an error removing a resource without a dispatcher")
+	}
+	if err := api.ReplaceResource(models["tag"], store.Resource(models["tag"])); err == nil {
+		t.Error("expected an error replacing a resource without a dispatcher")
+	}
+}
+
+// TestReplaceResourceWhileServing is meant to be run with -race
+func TestReplaceResourceWhileServing(t *testing.T) {
+	api, store, _ := memoryTestAPI(t)
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	handler := api.Handler()
+	models := memoryTestModels()
+
+	done := make(chan struct{})
+	var wait sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, path := range []string{"/v1/tag/t1", "/v1/user/u1/tag_id"} {
+					w := httptest.NewRecorder()
+					handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+					if w.Code != http.StatusOK {
+						t.Errorf("GET %s: expected status 200 while replacing, got %d", path, w.Code)
+						return
+					}
+				}
+				api.Routes()
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		if err := api.ReplaceResource(models["tag"], store.Resource(models["tag"])); err != nil {
+			t.Error(err)
+			break
+		}
+	}
+	close(done)
+	wait.Wait()
+}
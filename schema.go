@@ -0,0 +1,310 @@
+package api2go
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+	"gopkg.in/yaml.v3"
+)
+
+// Schema describes Api2GoModel resources in a JSON or YAML file, e.g.
+//
+//	tables:
+//	  - name: user
+//	    columns:
+//	      - {name: id, data_type: int(11), primary_key: true, auto_increment: true}
+//	      - {name: reference_id, data_type: varchar(64), unique: true}
+//	      - {name: email, data_type: varchar(100), indexed: true}
+//	relations:
+//	  - {subject: user, relation: has_many, object: tag}
+type Schema struct {
+	Tables    []TableSchema    `yaml:"tables"`
+	Relations []RelationSchema `yaml:"relations"`
+}
+
+// TableSchema describes one table, which becomes one resource
+type TableSchema struct {
+	Name string `yaml:"name"`
+	// Permission is the default permission of new rows
+	Permission int64          `yaml:"permission"`
+	Columns    []ColumnSchema `yaml:"columns"`
+}
+
+// ColumnSchema describes a column, see ColumnInfo for the meaning of the fields
+type ColumnSchema struct {
+	Name          string `yaml:"name"`
+	ColumnName    string `yaml:"column_name"`
+	APIName       string `yaml:"api_name"`
+	Description   string `yaml:"description"`
+	ColumnType    string `yaml:"column_type"`
+	DataType      string `yaml:"data_type"`
+	DefaultValue  string `yaml:"default_value"`
+	PrimaryKey    bool   `yaml:"primary_key"`
+	AutoIncrement bool   `yaml:"auto_increment"`
+	Indexed       bool   `yaml:"indexed"`
+	Unique        bool   `yaml:"unique"`
+	Nullable      bool   `yaml:"nullable"`
+	Permission    uint64 `yaml:"permission"`
+	HiddenFromAPI bool   `yaml:"hidden_from_api"`
+	// ForeignKey has the format "[datasource:]table(column)"
+	ForeignKey string         `yaml:"foreign_key"`
+	Options    []OptionSchema `yaml:"options"`
+}
+
+// OptionSchema is an allowed value of a column
+type OptionSchema struct {
+	Value     interface{} `yaml:"value"`
+	Label     string      `yaml:"label"`
+	ValueType string      `yaml:"value_type"`
+}
+
+// RelationSchema describes a relation like NewTableRelationWithNames
+type RelationSchema struct {
+	Subject     string       `yaml:"subject"`
+	SubjectName string       `yaml:"subject_name"`
+	Relation    RelationKind `yaml:"relation"`
+	Object      string       `yaml:"object"`
+	ObjectName  string       `yaml:"object_name"`
+}
+
+// SchemaError lists all problems found in a schema
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "invalid schema: " + strings.Join(e.Problems, "; ")
+}
+
+// A SourceFactory creates the data source of a table, e.g.
+//
+//	func(model Api2GoModel) interface{} { return NewSQLResource(db, dialect, model) }
+type SourceFactory func(model Api2GoModel) interface{}
+
+// ParseSchema reads a schema in YAML or JSON. Unknown keys are rejected, so
+// typos do not go unnoticed. The schema is validated.
+func ParseSchema(data []byte) (*Schema, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var schema Schema
+	if err := decoder.Decode(&schema); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.Validate(); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchemaFile reads a schema file in YAML or JSON
+func LoadSchemaFile(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// Validate returns a *SchemaError with all problems of the schema
+func (s *Schema) Validate() error {
+	var problems []string
+	problem := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	tables := map[string]bool{}
+	for i, table := range s.Tables {
+		if table.Name == "" {
+			problem("table %d has no name", i)
+			continue
+		}
+		if tables[table.Name] {
+			problem("table %s is defined twice", table.Name)
+		}
+		tables[table.Name] = true
+	}
+
+	for _, table := range s.Tables {
+		columns := map[string]bool{}
+		apiNames := map[string]bool{}
+		primaryKeys := 0
+		for i, column := range table.Columns {
+			if column.Name == "" {
+				problem("column %d of table %s has no name", i, table.Name)
+				continue
+			}
+			if columns[column.Name] {
+				problem("column %s of table %s is defined twice", column.Name, table.Name)
+			}
+			columns[column.Name] = true
+			if column.APIName != "" {
+				if apiNames[column.APIName] {
+					problem("api_name %s of table %s is used twice", column.APIName, table.Name)
+				}
+				apiNames[column.APIName] = true
+			}
+			if column.DataType == "" {
+				problem("column %s of table %s has no data_type", column.Name, table.Name)
+			}
+			if column.PrimaryKey {
+				primaryKeys++
+			}
+			if column.ForeignKey != "" {
+				foreignKey, err := parseForeignKey(column.ForeignKey)
+				if err != nil {
+					problem("column %s of table %s: %v", column.Name, table.Name, err)
+				} else if foreignKey.DataSource == "self" && !tables[foreignKey.Namespace] {
+					problem("column %s of table %s references unknown table %s", column.Name, table.Name, foreignKey.Namespace)
+				}
+			}
+		}
+		if primaryKeys != 1 {
+			problem("table %s needs exactly one primary key column, it has %d", table.Name, primaryKeys)
+		}
+	}
+
+	for i, relation := range s.Relations {
+		if !relation.Relation.Valid() {
+			problem("relation %d has unknown kind %q", i, relation.Relation)
+		}
+		if !tables[relation.Subject] {
+			problem("relation %d has unknown subject table %q", i, relation.Subject)
+		}
+		if !tables[relation.Object] {
+			problem("relation %d has unknown object table %q", i, relation.Object)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// parseForeignKey parses "[datasource:]table(column)" like ForeignKeyData.Scan
+func parseForeignKey(value string) (ForeignKeyData, error) {
+	var foreignKey ForeignKeyData
+	open := strings.Index(value, "(")
+	if open < 1 || !strings.HasSuffix(value, ")") || open == len(value)-2 {
+		return foreignKey, fmt.Errorf("foreign key %q must have the format [datasource:]table(column)", value)
+	}
+	if err := foreignKey.Scan([]byte(value)); err != nil {
+		return foreignKey, err
+	}
+	return foreignKey, nil
+}
+
+// Models returns an Api2GoModel for every table, with all relations the table
+// takes part in. The schema must be valid.
+func (s *Schema) Models() []Api2GoModel {
+	models := make([]Api2GoModel, 0, len(s.Tables))
+	for _, table := range s.Tables {
+		columns := make([]ColumnInfo, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			columns = append(columns, column.columnInfo())
+		}
+
+		var relations []TableRelation
+		for _, relation := range s.Relations {
+			if relation.Subject != table.Name && relation.Object != table.Name {
+				continue
+			}
+			tableRelation := NewTableRelation(relation.Subject, relation.Relation, relation.Object)
+			if relation.SubjectName != "" {
+				tableRelation.SubjectName = relation.SubjectName
+			}
+			if relation.ObjectName != "" {
+				tableRelation.ObjectName = relation.ObjectName
+			}
+			relations = append(relations, tableRelation)
+		}
+
+		models = append(models, NewApi2GoModel(table.Name, columns, table.Permission, relations))
+	}
+	return models
+}
+
+func (c ColumnSchema) columnInfo() ColumnInfo {
+	info := ColumnInfo{
+		Name:              c.Name,
+		ColumnName:        c.ColumnName,
+		APIName:           c.APIName,
+		ColumnDescription: c.Description,
+		ColumnType:        c.ColumnType,
+		DataType:          c.DataType,
+		DefaultValue:      c.DefaultValue,
+		IsPrimaryKey:      c.PrimaryKey,
+		IsAutoIncrement:   c.AutoIncrement,
+		IsIndexed:         c.Indexed,
+		IsUnique:          c.Unique,
+		IsNullable:        c.Nullable,
+		Permission:        c.Permission,
+		ExcludeFromApi:    c.HiddenFromAPI,
+	}
+	if info.ColumnName == "" {
+		info.ColumnName = c.Name
+	}
+	if c.ForeignKey != "" {
+		info.IsForeignKey = true
+		info.ForeignKeyData, _ = parseForeignKey(c.ForeignKey)
+	}
+	for _, option := range c.Options {
+		info.Options = append(info.Options, ValueOptions{
+			ValueType: option.ValueType,
+			Value:     option.Value,
+			Label:     option.Label,
+		})
+	}
+	return info
+}
+
+// Register validates the schema and adds a resource for every table to api,
+// with the data source created by factory. Either all tables are added or
+// none: tables which are registered already or have members the naming
+// strategy turns into reserved names are refused with a *SchemaError,
+// conflicting routes with the error of the dispatcher. Invalid relations
+// and relations which conflict with the registered resources are returned
+// as *RelationError, joined if there are several.
+func (s *Schema) Register(api *API, factory SourceFactory) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	models := s.Models()
+
+	api.changeMutex.Lock()
+	defer api.changeMutex.Unlock()
+
+	var problems []string
+	for _, model := range models {
+		if _, ok := api.findResource(resourceName(model)); ok {
+			problems = append(problems, fmt.Sprintf("resource %s is already registered", resourceName(model)))
+		}
+		if err := api.memberNameError(model); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	prototypes := make([]jsonapi.MarshalIdentifier, len(models))
+	for i, model := range models {
+		prototypes[i] = model
+	}
+	if err := api.validateGraph(prototypes...); err != nil {
+		return err
+	}
+
+	// a single batch, so requests see either none or all of the tables
+	return api.batch(func() error {
+		for _, model := range models {
+			api.addResource(model, factory(model))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,165 @@
+package api2go_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+)
+
+const validSchema = `
+tables:
+  - name: user
+    columns:
+      - {name: id, data_type: int(11), primary_key: true, auto_increment: true}
+      - {name: reference_id, data_type: varchar(64), unique: true}
+      - {name: email, data_type: varchar(100), indexed: true}
+  - name: tag
+    columns:
+      - {name: id, data_type: int(11), primary_key: true, auto_increment: true}
+      - {name: reference_id, data_type: varchar(64), unique: true}
+      - {name: owner, data_type: int(11), foreign_key: "user(id)"}
+relations:
+  - {subject: user, relation: has_many, object: tag}
+`
+
+func TestParseSchema(t *testing.T) {
+	schema, err := api2go.ParseSchema([]byte(validSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	models := schema.Models()
+	if len(models) != 2 || models[0].GetTableName() != "user" || models[1].GetTableName() != "tag" {
+		t.Fatalf("expected the models user and tag, got %v", models)
+	}
+	if relations := models[1].GetRelations(); len(relations) != 1 || relations[0].GetSubject() != "user" {
+		t.Errorf("expected the tag to take part in the relation to user, got %v", relations)
+	}
+	owner := models[1].GetColumnMap()["owner"]
+	if !owner.IsForeignKey || owner.ForeignKeyData.Namespace != "user" || owner.ForeignKeyData.KeyName != "id" {
+		t.Errorf("expected owner to reference user(id), got %+v", owner.ForeignKeyData)
+	}
+
+	json := `{"tables": [{"name": "user", "columns": [{"name": "id", "data_type": "int(11)", "primary_key": true}]}]}`
+	if _, err := api2go.ParseSchema([]byte(json)); err != nil {
+		t.Errorf("expected a JSON schema to be accepted, got %v", err)
+	}
+}
+
+func TestParseSchemaErrors(t *testing.T) {
+	table := func(name string, columns ...string) string {
+		return "  - name: " + name + "\n    columns:\n      - {name: id, data_type: int(11), primary_key: true}\n" + strings.Join(columns, "")
+	}
+
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"unknown key", "tables:\n" + table("user") + "    colums: []\n", "colums"},
+		{"unknown column key", "tables:\n" + table("user", "      - {name: a, data_type: int(11), nulable: true}\n"), "nulable"},
+		{"duplicate table", "tables:\n" + table("user") + table("user"), "table user is defined twice"},
+		{"duplicate column", "tables:\n" + table("user", "      - {name: id, data_type: int(11)}\n"), "column id of table user is defined twice"},
+		{"missing data type", "tables:\n" + table("user", "      - {name: a}\n"), "column a of table user has no data_type"},
+		{"no primary key", "tables:\n  - name: user\n    columns:\n      - {name: a, data_type: int(11)}\n", "needs exactly one primary key"},
+		{"malformed foreign key", "tables:\n" + table("user", "      - {name: a, data_type: int(11), foreign_key: user}\n"), "must have the format"},
+		{"foreign key without column", "tables:\n" + table("user", "      - {name: a, data_type: int(11), foreign_key: user()}\n"), "must have the format"},
+		{"foreign key to unknown table", "tables:\n" + table("user", "      - {name: a, data_type: int(11), foreign_key: group(id)}\n"), "references unknown table group"},
+		{"unknown relation kind", "tables:\n" + table("user") + table("tag") + "relations:\n  - {subject: user, relation: owns, object: tag}\n", `unknown kind "owns"`},
+		{"unknown relation table", "tables:\n" + table("user") + "relations:\n  - {subject: user, relation: has_many, object: tag}\n", `unknown object table "tag"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := api2go.ParseSchema([]byte(test.schema))
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("expected an error containing %q, got %v", test.want, err)
+			}
+		})
+	}
+}
+
+func TestSchemaRegister(t *testing.T) {
+	schema, err := api2go.ParseSchema([]byte(validSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := api2go.NewMemoryStore()
+	factory := func(model api2go.Api2GoModel) interface{} { return store.Resource(model) }
+
+	api := api2go.NewAPI("v1")
+	if err := schema.Register(api, factory); err != nil {
+		t.Fatal(err)
+	}
+	c := testClient{t, api.Handler()}
+	c.do("GET", "user", nil).expect(http.StatusOK)
+	c.do("GET", "tag", nil).expect(http.StatusOK)
+}
+
+func TestSchemaRegisterAfterOtherResources(t *testing.T) {
+	schema, err := api2go.ParseSchema([]byte(validSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := api2go.NewMemoryStore()
+	factory := func(model api2go.Api2GoModel) interface{} { return store.Resource(model) }
+
+	api := api2go.NewAPI("v1")
+	// the comment a post belongs to is not registered yet, which only the
+	// relations of the schema need
+	post := relationModel("post", api2go.NewBelongsTo("post", "comment"))
+	api.AddResource(post, factory(post))
+	if err := schema.Register(api, factory); err != nil {
+		t.Fatalf("expected the schema to be registered, got %v", err)
+	}
+}
+
+func TestSchemaRegisterIsAllOrNothing(t *testing.T) {
+	schema, err := api2go.ParseSchema([]byte(validSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := api2go.NewMemoryStore()
+	factory := func(model api2go.Api2GoModel) interface{} { return store.Resource(model) }
+
+	// resourceNames returns the names of the resources with routes
+	resourceNames := func(api *api2go.API) map[string]bool {
+		names := make(map[string]bool)
+		for _, route := range api.Routes() {
+			names[route.Resource] = true
+		}
+		return names
+	}
+
+	t.Run("taken name", func(t *testing.T) {
+		api := api2go.NewAPI("v1")
+		tag := schema.Models()[1]
+		api.AddResource(tag, factory(tag))
+
+		err := schema.Register(api, factory)
+		var schemaError *api2go.SchemaError
+		if !errors.As(err, &schemaError) || !strings.Contains(err.Error(), "tag is already registered") {
+			t.Fatalf("expected a SchemaError for the taken name, got %v", err)
+		}
+		if names := resourceNames(api); names["user"] {
+			t.Errorf("expected user not to be added, got %v", names)
+		}
+	})
+
+	t.Run("conflicting routes", func(t *testing.T) {
+		api := api2go.NewAPI("v1")
+		// the wildcard :name conflicts with the :id of the tag routes
+		api.Router().Handle("GET", "/v1/tag/:name/icon", func(w http.ResponseWriter, r *http.Request, params map[string]string) {})
+
+		if err := schema.Register(api, factory); err == nil {
+			t.Fatal("expected an error for conflicting routes")
+		}
+		if names := resourceNames(api); len(names) != 0 {
+			t.Errorf("expected no resource to be added, got %v", names)
+		}
+		c := testClient{t, api.Handler()}
+		c.do("GET", "user", nil).expect(http.StatusNotFound)
+	})
+}
@@ -0,0 +1,580 @@
+package api2go
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JoinType is the SQL keyword used to join a table
+type JoinType string
+
+// The supported join types
+const (
+	InnerJoin JoinType = "JOIN"
+	LeftJoin  JoinType = "LEFT JOIN"
+)
+
+// JoinCondition compares two qualified columns (`alias.column`) for equality
+type JoinCondition struct {
+	Left  string
+	Right string
+}
+
+// JoinClause is a single JOIN of Table under the name Alias
+type JoinClause struct {
+	Type  JoinType
+	Table string
+	Alias string
+	On    []JoinCondition
+}
+
+// SQL renders the join clause for the given dialect
+func (j JoinClause) SQL(d Dialect) string {
+	joinType := j.Type
+	if joinType == "" {
+		joinType = InnerJoin
+	}
+
+	var b strings.Builder
+	b.WriteString(string(joinType))
+	b.WriteString(" ")
+	b.WriteString(d.QuoteIdentifier(j.Table))
+	if j.Alias != "" && j.Alias != j.Table {
+		b.WriteString(" AS ")
+		b.WriteString(d.QuoteIdentifier(j.Alias))
+	}
+
+	for i, condition := range j.On {
+		if i == 0 {
+			b.WriteString(" ON ")
+		} else {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(quoteColumn(d, condition.Left))
+		b.WriteString(" = ")
+		b.WriteString(quoteColumn(d, condition.Right))
+	}
+
+	return b.String()
+}
+
+// uniqueAlias returns alias, or alias with a numeric suffix if it is already taken
+func uniqueAlias(alias string, taken ...string) string {
+	candidate := alias
+	for i := 2; ; i++ {
+		clash := false
+		for _, t := range taken {
+			if t == candidate {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			return candidate
+		}
+		candidate = alias + "_" + strconv.Itoa(i)
+	}
+}
+
+// JoinClauses returns the joins needed to reach the object of the relation when
+// the subject table is selected under the alias `from`.
+//
+// has_one and belongs_to relations join the object table directly on the foreign
+// key column in the subject table, has_many and has_many_and_belongs_to_many
+// relations go through the join table named by GetJoinTableName, on the
+// columns named by GetJoinColumnNames. A has_many
+// relation keeps no foreign key in the object table, so both join the same way.
+// The object table is aliased with the object name, a numeric suffix is added
+// if that name collides with `from` (self relations).
+func (tr *TableRelation) JoinClauses(from string) ([]JoinClause, error) {
+	switch tr.Relation {
+	case RelationHasOne, RelationBelongsTo:
+		alias := uniqueAlias(tr.GetObjectName(), from)
+		return []JoinClause{{
+			Type:  InnerJoin,
+			Table: tr.GetObject(),
+			Alias: alias,
+			On:    []JoinCondition{{Left: from + "." + tr.GetObjectName(), Right: alias + ".id"}},
+		}}, nil
+	case RelationHasMany, RelationManyToMany:
+		subjectColumn, objectColumn := tr.GetJoinColumnNames()
+		joinAlias := uniqueAlias(tr.GetJoinTableName(), from)
+		alias := uniqueAlias(tr.GetObjectName(), from, joinAlias)
+		return []JoinClause{
+			{
+				Type:  InnerJoin,
+				Table: tr.GetJoinTableName(),
+				Alias: joinAlias,
+				On:    []JoinCondition{{Left: joinAlias + "." + subjectColumn, Right: from + ".id"}},
+			},
+			{
+				Type:  InnerJoin,
+				Table: tr.GetObject(),
+				Alias: alias,
+				On:    []JoinCondition{{Left: joinAlias + "." + objectColumn, Right: alias + ".id"}},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("join not implemented for relation %v", tr)
+}
+
+// ReverseJoinClauses returns the joins needed to reach the subject of the relation
+// when the object table is selected under the alias `from`.
+// The subject table is aliased with the subject name, see JoinClauses.
+func (tr *TableRelation) ReverseJoinClauses(from string) ([]JoinClause, error) {
+	switch tr.Relation {
+	case RelationHasOne, RelationBelongsTo:
+		alias := uniqueAlias(tr.GetSubjectName(), from)
+		return []JoinClause{{
+			Type:  InnerJoin,
+			Table: tr.GetSubject(),
+			Alias: alias,
+			On:    []JoinCondition{{Left: alias + "." + tr.GetObjectName(), Right: from + ".id"}},
+		}}, nil
+	case RelationHasMany, RelationManyToMany:
+		subjectColumn, objectColumn := tr.GetJoinColumnNames()
+		joinAlias := uniqueAlias(tr.GetJoinTableName(), from)
+		alias := uniqueAlias(tr.GetSubjectName(), from, joinAlias)
+		return []JoinClause{
+			{
+				Type:  InnerJoin,
+				Table: tr.GetJoinTableName(),
+				Alias: joinAlias,
+				On:    []JoinCondition{{Left: joinAlias + "." + objectColumn, Right: from + ".id"}},
+			},
+			{
+				Type:  InnerJoin,
+				Table: tr.GetSubject(),
+				Alias: alias,
+				On:    []JoinCondition{{Left: joinAlias + "." + subjectColumn, Right: alias + ".id"}},
+			},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("join not implemented for relation %v", tr)
+}
+
+// Operator compares a column with the values of a condition
+type Operator string
+
+// The operators accepted in conditions. Other operators are rejected by Build,
+// they would be written into the statement unescaped.
+const (
+	OpEqual          Operator = "="
+	OpNotEqual       Operator = "<>"
+	OpLess           Operator = "<"
+	OpLessOrEqual    Operator = "<="
+	OpGreater        Operator = ">"
+	OpGreaterOrEqual Operator = ">="
+	OpLike           Operator = "LIKE"
+	OpIn             Operator = "IN"
+	OpIsNull         Operator = "IS NULL"
+)
+
+// Valid reports whether o is one of the supported operators
+func (o Operator) Valid() bool {
+	switch o {
+	case OpEqual, OpNotEqual, OpLess, OpLessOrEqual, OpGreater, OpGreaterOrEqual, OpLike, OpIn, OpIsNull:
+		return true
+	}
+	return false
+}
+
+type whereClause struct {
+	column   string
+	operator Operator
+	values   []interface{}
+}
+
+// newWhereClause compares column with value. OpIn takes a []interface{} of
+// values and OpIsNull ignores value.
+func newWhereClause(column string, operator Operator, value interface{}) whereClause {
+	switch operator {
+	case OpIn:
+		if values, ok := value.([]interface{}); ok {
+			return whereClause{column: column, operator: operator, values: values}
+		}
+	case OpIsNull:
+		return whereClause{column: column, operator: operator}
+	}
+	return whereClause{column: column, operator: operator, values: []interface{}{value}}
+}
+
+type selectColumn struct {
+	column string
+	as     string
+}
+
+type orderClause struct {
+	column     string
+	descending bool
+}
+
+// SelectQuery builds a SELECT statement for one dialect. Values passed to Where
+// are never written into the statement, they are returned as arguments
+// alongside the placeholders of the dialect.
+type SelectQuery struct {
+	dialect Dialect
+	table   string
+	alias   string
+	columns []selectColumn
+	joins   []JoinClause
+	where   []whereClause
+	orderBy []orderClause
+	limit   uint64
+	offset  uint64
+}
+
+// NewSelectQuery starts a query on table, selected under alias.
+// An empty alias selects the table under its own name.
+func NewSelectQuery(d Dialect, table, alias string) *SelectQuery {
+	if alias == "" {
+		alias = table
+	}
+	return &SelectQuery{dialect: d, table: table, alias: alias}
+}
+
+// Alias returns the name the base table is selected under
+func (q *SelectQuery) Alias() string {
+	return q.alias
+}
+
+// Columns adds (optionally qualified) columns to the select list.
+// Without any columns `alias.*` is selected.
+func (q *SelectQuery) Columns(columns ...string) *SelectQuery {
+	for _, column := range columns {
+		q.columns = append(q.columns, selectColumn{column: column})
+	}
+	return q
+}
+
+// ColumnAs adds a (optionally qualified) column to the select list under the name as
+func (q *SelectQuery) ColumnAs(column, as string) *SelectQuery {
+	q.columns = append(q.columns, selectColumn{column: column, as: as})
+	return q
+}
+
+// Join adds join clauses
+func (q *SelectQuery) Join(joins ...JoinClause) *SelectQuery {
+	q.joins = append(q.joins, joins...)
+	return q
+}
+
+// JoinRelation joins the object of relation, the base table must be its subject
+func (q *SelectQuery) JoinRelation(relation TableRelation) error {
+	joins, err := relation.JoinClauses(q.alias)
+	if err != nil {
+		return err
+	}
+	q.Join(joins...)
+	return nil
+}
+
+// JoinReverseRelation joins the subject of relation, the base table must be its object
+func (q *SelectQuery) JoinReverseRelation(relation TableRelation) error {
+	joins, err := relation.ReverseJoinClauses(q.alias)
+	if err != nil {
+		return err
+	}
+	q.Join(joins...)
+	return nil
+}
+
+// Where adds a `column operator ?` condition, all conditions are combined with AND.
+// Build fails for operators which are not Valid.
+func (q *SelectQuery) Where(column string, operator Operator, value interface{}) *SelectQuery {
+	q.where = append(q.where, newWhereClause(column, operator, value))
+	return q
+}
+
+// WhereIn adds a `column IN (?, ...)` condition. An empty list matches no rows.
+func (q *SelectQuery) WhereIn(column string, values []interface{}) *SelectQuery {
+	q.where = append(q.where, whereClause{column: column, operator: OpIn, values: values})
+	return q
+}
+
+// OrderBy adds a sort column
+func (q *SelectQuery) OrderBy(column string, descending bool) *SelectQuery {
+	q.orderBy = append(q.orderBy, orderClause{column: column, descending: descending})
+	return q
+}
+
+// Limit sets the maximum number of rows, 0 means no limit
+func (q *SelectQuery) Limit(limit uint64) *SelectQuery {
+	q.limit = limit
+	return q
+}
+
+// Offset sets the number of rows to skip
+func (q *SelectQuery) Offset(offset uint64) *SelectQuery {
+	q.offset = offset
+	return q
+}
+
+// Build returns the statement and its arguments
+func (q *SelectQuery) Build() (string, []interface{}, error) {
+	d := q.dialect
+	var b strings.Builder
+	args := make([]interface{}, 0)
+
+	b.WriteString("SELECT ")
+	if len(q.columns) == 0 {
+		b.WriteString(quoteColumn(d, q.alias+".*"))
+	} else {
+		for i, column := range q.columns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(quoteColumn(d, column.column))
+			if column.as != "" {
+				b.WriteString(" AS ")
+				b.WriteString(d.QuoteIdentifier(column.as))
+			}
+		}
+	}
+
+	b.WriteString(" FROM ")
+	b.WriteString(d.QuoteIdentifier(q.table))
+	if q.alias != q.table {
+		b.WriteString(" AS ")
+		b.WriteString(d.QuoteIdentifier(q.alias))
+	}
+
+	for _, join := range q.joins {
+		b.WriteString(" ")
+		b.WriteString(join.SQL(d))
+	}
+
+	args, err := writeWhere(&b, d, q.where, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	for i, order := range q.orderBy {
+		if i == 0 {
+			b.WriteString(" ORDER BY ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(quoteColumn(d, order.column))
+		if order.descending {
+			b.WriteString(" DESC")
+		} else {
+			b.WriteString(" ASC")
+		}
+	}
+
+	if q.limit > 0 {
+		b.WriteString(" LIMIT ")
+		b.WriteString(strconv.FormatUint(q.limit, 10))
+	}
+	if q.offset > 0 {
+		// mysql and sqlite do not accept an OFFSET without a LIMIT
+		if q.limit == 0 && d.Name() == "mysql" {
+			b.WriteString(" LIMIT 18446744073709551615")
+		} else if q.limit == 0 && d.Name() == "sqlite" {
+			b.WriteString(" LIMIT -1")
+		}
+		b.WriteString(" OFFSET ")
+		b.WriteString(strconv.FormatUint(q.offset, 10))
+	}
+
+	return b.String(), args, nil
+}
+
+// CountQuery returns a `SELECT COUNT(*)` over the same tables and conditions,
+// ignoring order, limit and offset
+func (q *SelectQuery) CountQuery() (string, []interface{}, error) {
+	d := q.dialect
+	var b strings.Builder
+
+	b.WriteString("SELECT COUNT(*) FROM ")
+	b.WriteString(d.QuoteIdentifier(q.table))
+	if q.alias != q.table {
+		b.WriteString(" AS ")
+		b.WriteString(d.QuoteIdentifier(q.alias))
+	}
+	for _, join := range q.joins {
+		b.WriteString(" ")
+		b.WriteString(join.SQL(d))
+	}
+	args, err := writeWhere(&b, d, q.where, make([]interface{}, 0))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return b.String(), args, nil
+}
+
+func writeWhere(b *strings.Builder, d Dialect, where []whereClause, args []interface{}) ([]interface{}, error) {
+	for i, condition := range where {
+		if !condition.operator.Valid() {
+			return nil, fmt.Errorf("unsupported operator %q in condition on %s", string(condition.operator), condition.column)
+		}
+
+		if i == 0 {
+			b.WriteString(" WHERE ")
+		} else {
+			b.WriteString(" AND ")
+		}
+
+		if condition.operator == OpIn {
+			if len(condition.values) == 0 {
+				b.WriteString("1 = 0")
+				continue
+			}
+			b.WriteString(quoteColumn(d, condition.column))
+			b.WriteString(" IN (")
+			for j, value := range condition.values {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				args = append(args, value)
+				b.WriteString(d.Placeholder(len(args)))
+			}
+			b.WriteString(")")
+			continue
+		}
+
+		b.WriteString(quoteColumn(d, condition.column))
+		b.WriteString(" ")
+		b.WriteString(string(condition.operator))
+		if condition.operator == OpIsNull {
+			continue
+		}
+		b.WriteString(" ")
+		args = append(args, condition.values[0])
+		b.WriteString(d.Placeholder(len(args)))
+	}
+	return args, nil
+}
+
+type assignment struct {
+	column string
+	value  interface{}
+}
+
+// InsertQuery builds an INSERT statement for one row
+type InsertQuery struct {
+	dialect Dialect
+	table   string
+	values  []assignment
+}
+
+// NewInsertQuery starts an insert into table
+func NewInsertQuery(d Dialect, table string) *InsertQuery {
+	return &InsertQuery{dialect: d, table: table}
+}
+
+// Set adds a column value
+func (q *InsertQuery) Set(column string, value interface{}) *InsertQuery {
+	q.values = append(q.values, assignment{column: column, value: value})
+	return q
+}
+
+// Build returns the statement and its arguments
+func (q *InsertQuery) Build() (string, []interface{}) {
+	d := q.dialect
+	columns := make([]string, len(q.values))
+	placeholders := make([]string, len(q.values))
+	args := make([]interface{}, len(q.values))
+	for i, value := range q.values {
+		columns[i] = d.QuoteIdentifier(value.column)
+		placeholders[i] = d.Placeholder(i + 1)
+		args[i] = value.value
+	}
+
+	return "INSERT INTO " + d.QuoteIdentifier(q.table) + " (" + strings.Join(columns, ", ") +
+		") VALUES (" + strings.Join(placeholders, ", ") + ")", args
+}
+
+// UpdateQuery builds an UPDATE statement
+type UpdateQuery struct {
+	dialect Dialect
+	table   string
+	values  []assignment
+	where   []whereClause
+}
+
+// NewUpdateQuery starts an update of table
+func NewUpdateQuery(d Dialect, table string) *UpdateQuery {
+	return &UpdateQuery{dialect: d, table: table}
+}
+
+// Set adds a column value
+func (q *UpdateQuery) Set(column string, value interface{}) *UpdateQuery {
+	q.values = append(q.values, assignment{column: column, value: value})
+	return q
+}
+
+// Where adds a `column operator ?` condition, all conditions are combined with AND.
+// Build fails for operators which are not Valid.
+func (q *UpdateQuery) Where(column string, operator Operator, value interface{}) *UpdateQuery {
+	q.where = append(q.where, newWhereClause(column, operator, value))
+	return q
+}
+
+// Build returns the statement and its arguments
+func (q *UpdateQuery) Build() (string, []interface{}, error) {
+	d := q.dialect
+	var b strings.Builder
+	args := make([]interface{}, 0, len(q.values))
+
+	b.WriteString("UPDATE ")
+	b.WriteString(d.QuoteIdentifier(q.table))
+	b.WriteString(" SET ")
+	for i, value := range q.values {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		args = append(args, value.value)
+		b.WriteString(d.QuoteIdentifier(value.column))
+		b.WriteString(" = ")
+		b.WriteString(d.Placeholder(len(args)))
+	}
+	args, err := writeWhere(&b, d, q.where, args)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return b.String(), args, nil
+}
+
+// DeleteQuery builds a DELETE statement
+type DeleteQuery struct {
+	dialect Dialect
+	table   string
+	where   []whereClause
+}
+
+// NewDeleteQuery starts a delete from table
+func NewDeleteQuery(d Dialect, table string) *DeleteQuery {
+	return &DeleteQuery{dialect: d, table: table}
+}
+
+// Where adds a `column operator ?` condition, all conditions are combined with AND.
+// Build fails for operators which are not Valid.
+func (q *DeleteQuery) Where(column string, operator Operator, value interface{}) *DeleteQuery {
+	q.where = append(q.where, newWhereClause(column, operator, value))
+	return q
+}
+
+// WhereIn adds a `column IN (?, ...)` condition. An empty list matches no rows.
+func (q *DeleteQuery) WhereIn(column string, values []interface{}) *DeleteQuery {
+	q.where = append(q.where, whereClause{column: column, operator: OpIn, values: values})
+	return q
+}
+
+// Build returns the statement and its arguments
+func (q *DeleteQuery) Build() (string, []interface{}, error) {
+	var b strings.Builder
+	b.WriteString("DELETE FROM ")
+	b.WriteString(q.dialect.QuoteIdentifier(q.table))
+	args, err := writeWhere(&b, q.dialect, q.where, make([]interface{}, 0))
+	if err != nil {
+		return "", nil, err
+	}
+	return b.String(), args, nil
+}
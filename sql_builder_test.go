@@ -0,0 +1,276 @@
+package api2go
+
+import (
+	"strings"
+	"testing"
+)
+
+func joinSQL(d Dialect, joins []JoinClause) string {
+	parts := make([]string, len(joins))
+	for i, join := range joins {
+		parts[i] = join.SQL(d)
+	}
+	return strings.Join(parts, " ")
+}
+
+// named sets the name of the object of relation
+func named(relation TableRelation, objectName string) TableRelation {
+	relation.ObjectName = objectName
+	return relation
+}
+
+func TestJoinClauses(t *testing.T) {
+	tests := []struct {
+		name     string
+		relation TableRelation
+		from     string
+		reverse  bool
+		want     map[string]string
+	}{
+		{
+			name:     "has_one",
+			relation: named(NewTableRelation("user", "has_one", "account"), "account_id"),
+			from:     "user",
+			want: map[string]string{
+				"mysql":    "JOIN `account` AS `account_id` ON `user`.`account_id` = `account_id`.`id`",
+				"postgres": `JOIN "account" AS "account_id" ON "user"."account_id" = "account_id"."id"`,
+				"sqlite":   `JOIN "account" AS "account_id" ON "user"."account_id" = "account_id"."id"`,
+			},
+		},
+		{
+			name:     "has_one reverse",
+			relation: named(NewTableRelation("user", "has_one", "account"), "account_id"),
+			from:     "account",
+			reverse:  true,
+			want: map[string]string{
+				"mysql":    "JOIN `user` AS `user_id` ON `user_id`.`account_id` = `account`.`id`",
+				"postgres": `JOIN "user" AS "user_id" ON "user_id"."account_id" = "account"."id"`,
+				"sqlite":   `JOIN "user" AS "user_id" ON "user_id"."account_id" = "account"."id"`,
+			},
+		},
+		{
+			name:     "belongs_to",
+			relation: named(NewTableRelation("comment", "belongs_to", "post"), "post"),
+			from:     "comment",
+			want: map[string]string{
+				"mysql":    "JOIN `post` ON `comment`.`post` = `post`.`id`",
+				"postgres": `JOIN "post" ON "comment"."post" = "post"."id"`,
+				"sqlite":   `JOIN "post" ON "comment"."post" = "post"."id"`,
+			},
+		},
+		{
+			name:     "belongs_to reverse",
+			relation: named(NewTableRelation("comment", "belongs_to", "post"), "post"),
+			from:     "post",
+			reverse:  true,
+			want: map[string]string{
+				"mysql":    "JOIN `comment` AS `comment_id` ON `comment_id`.`post` = `post`.`id`",
+				"postgres": `JOIN "comment" AS "comment_id" ON "comment_id"."post" = "post"."id"`,
+				"sqlite":   `JOIN "comment" AS "comment_id" ON "comment_id"."post" = "post"."id"`,
+			},
+		},
+		{
+			name:     "has_many through the join table",
+			relation: named(NewTableRelation("user", "has_many", "post"), "posts"),
+			from:     "user",
+			want: map[string]string{
+				"mysql": "JOIN `user_user_id_has_post_posts` ON `user_user_id_has_post_posts`.`user_id` = `user`.`id` " +
+					"JOIN `post` AS `posts` ON `user_user_id_has_post_posts`.`posts` = `posts`.`id`",
+				"postgres": `JOIN "user_user_id_has_post_posts" ON "user_user_id_has_post_posts"."user_id" = "user"."id" ` +
+					`JOIN "post" AS "posts" ON "user_user_id_has_post_posts"."posts" = "posts"."id"`,
+				"sqlite": `JOIN "user_user_id_has_post_posts" ON "user_user_id_has_post_posts"."user_id" = "user"."id" ` +
+					`JOIN "post" AS "posts" ON "user_user_id_has_post_posts"."posts" = "posts"."id"`,
+			},
+		},
+		{
+			name:     "has_many reverse through the join table",
+			relation: named(NewTableRelation("user", "has_many", "post"), "posts"),
+			from:     "post",
+			reverse:  true,
+			want: map[string]string{
+				"mysql": "JOIN `user_user_id_has_post_posts` ON `user_user_id_has_post_posts`.`posts` = `post`.`id` " +
+					"JOIN `user` AS `user_id` ON `user_user_id_has_post_posts`.`user_id` = `user_id`.`id`",
+				"postgres": `JOIN "user_user_id_has_post_posts" ON "user_user_id_has_post_posts"."posts" = "post"."id" ` +
+					`JOIN "user" AS "user_id" ON "user_user_id_has_post_posts"."user_id" = "user_id"."id"`,
+				"sqlite": `JOIN "user_user_id_has_post_posts" ON "user_user_id_has_post_posts"."posts" = "post"."id" ` +
+					`JOIN "user" AS "user_id" ON "user_user_id_has_post_posts"."user_id" = "user_id"."id"`,
+			},
+		},
+		{
+			name:     "many_to_many",
+			relation: named(NewTableRelation("post", "has_many_and_belongs_to_many", "tag"), "tags"),
+			from:     "post",
+			want: map[string]string{
+				"mysql": "JOIN `post_post_id_has_tag_tags` ON `post_post_id_has_tag_tags`.`post_id` = `post`.`id` " +
+					"JOIN `tag` AS `tags` ON `post_post_id_has_tag_tags`.`tags` = `tags`.`id`",
+				"postgres": `JOIN "post_post_id_has_tag_tags" ON "post_post_id_has_tag_tags"."post_id" = "post"."id" ` +
+					`JOIN "tag" AS "tags" ON "post_post_id_has_tag_tags"."tags" = "tags"."id"`,
+				"sqlite": `JOIN "post_post_id_has_tag_tags" ON "post_post_id_has_tag_tags"."post_id" = "post"."id" ` +
+					`JOIN "tag" AS "tags" ON "post_post_id_has_tag_tags"."tags" = "tags"."id"`,
+			},
+		},
+		{
+			name:     "many_to_many reverse",
+			relation: named(NewTableRelation("post", "has_many_and_belongs_to_many", "tag"), "tags"),
+			from:     "tag",
+			reverse:  true,
+			want: map[string]string{
+				"mysql": "JOIN `post_post_id_has_tag_tags` ON `post_post_id_has_tag_tags`.`tags` = `tag`.`id` " +
+					"JOIN `post` AS `post_id` ON `post_post_id_has_tag_tags`.`post_id` = `post_id`.`id`",
+				"postgres": `JOIN "post_post_id_has_tag_tags" ON "post_post_id_has_tag_tags"."tags" = "tag"."id" ` +
+					`JOIN "post" AS "post_id" ON "post_post_id_has_tag_tags"."post_id" = "post_id"."id"`,
+				"sqlite": `JOIN "post_post_id_has_tag_tags" ON "post_post_id_has_tag_tags"."tags" = "tag"."id" ` +
+					`JOIN "post" AS "post_id" ON "post_post_id_has_tag_tags"."post_id" = "post_id"."id"`,
+			},
+		},
+		{
+			name:     "self has_one",
+			relation: named(NewTableRelation("employee", "has_one", "employee"), "manager"),
+			from:     "employee",
+			want: map[string]string{
+				"mysql":    "JOIN `employee` AS `manager` ON `employee`.`manager` = `manager`.`id`",
+				"postgres": `JOIN "employee" AS "manager" ON "employee"."manager" = "manager"."id"`,
+				"sqlite":   `JOIN "employee" AS "manager" ON "employee"."manager" = "manager"."id"`,
+			},
+		},
+		{
+			// the foreign key column is named like the table, so the alias
+			// of the parent collides with the selected table
+			name:     "self belongs_to named after the table",
+			relation: named(NewTableRelation("category", "belongs_to", "category"), "category"),
+			from:     "category",
+			want: map[string]string{
+				"mysql":    "JOIN `category` AS `category_2` ON `category`.`category` = `category_2`.`id`",
+				"postgres": `JOIN "category" AS "category_2" ON "category"."category" = "category_2"."id"`,
+				"sqlite":   `JOIN "category" AS "category_2" ON "category"."category" = "category_2"."id"`,
+			},
+		},
+		{
+			name:     "self belongs_to reverse",
+			relation: named(NewTableRelation("employee", "belongs_to", "employee"), "manager"),
+			from:     "employee",
+			reverse:  true,
+			want: map[string]string{
+				"mysql":    "JOIN `employee` AS `employee_id` ON `employee_id`.`manager` = `employee`.`id`",
+				"postgres": `JOIN "employee" AS "employee_id" ON "employee_id"."manager" = "employee"."id"`,
+				"sqlite":   `JOIN "employee" AS "employee_id" ON "employee_id"."manager" = "employee"."id"`,
+			},
+		},
+		{
+			name:     "self has_many",
+			relation: named(NewTableRelation("employee", "has_many", "employee"), "reports"),
+			from:     "employee",
+			want: map[string]string{
+				"mysql": "JOIN `employee_employee_id_has_employee_reports` ON `employee_employee_id_has_employee_reports`.`employee_id` = `employee`.`id` " +
+					"JOIN `employee` AS `reports` ON `employee_employee_id_has_employee_reports`.`reports` = `reports`.`id`",
+				"postgres": `JOIN "employee_employee_id_has_employee_reports" ON "employee_employee_id_has_employee_reports"."employee_id" = "employee"."id" ` +
+					`JOIN "employee" AS "reports" ON "employee_employee_id_has_employee_reports"."reports" = "reports"."id"`,
+				"sqlite": `JOIN "employee_employee_id_has_employee_reports" ON "employee_employee_id_has_employee_reports"."employee_id" = "employee"."id" ` +
+					`JOIN "employee" AS "reports" ON "employee_employee_id_has_employee_reports"."reports" = "reports"."id"`,
+			},
+		},
+		{
+			name:     "self many_to_many reverse",
+			relation: named(NewTableRelation("employee", "has_many_and_belongs_to_many", "employee"), "friends"),
+			from:     "employee",
+			reverse:  true,
+			want: map[string]string{
+				"mysql": "JOIN `employee_employee_id_has_employee_friends` ON `employee_employee_id_has_employee_friends`.`friends` = `employee`.`id` " +
+					"JOIN `employee` AS `employee_id` ON `employee_employee_id_has_employee_friends`.`employee_id` = `employee_id`.`id`",
+				"postgres": `JOIN "employee_employee_id_has_employee_friends" ON "employee_employee_id_has_employee_friends"."friends" = "employee"."id" ` +
+					`JOIN "employee" AS "employee_id" ON "employee_employee_id_has_employee_friends"."employee_id" = "employee_id"."id"`,
+				"sqlite": `JOIN "employee_employee_id_has_employee_friends" ON "employee_employee_id_has_employee_friends"."friends" = "employee"."id" ` +
+					`JOIN "employee" AS "employee_id" ON "employee_employee_id_has_employee_friends"."employee_id" = "employee_id"."id"`,
+			},
+		},
+		{
+			name:     "self many_to_many with default names",
+			relation: NewTableRelation("employee", "has_many_and_belongs_to_many", "employee"),
+			from:     "employee",
+			want: map[string]string{
+				"mysql": "JOIN `employee_employee_id_has_employee_employee_id` ON `employee_employee_id_has_employee_employee_id`.`employee_id` = `employee`.`id` " +
+					"JOIN `employee` AS `employee_id` ON `employee_employee_id_has_employee_employee_id`.`employee_id_2` = `employee_id`.`id`",
+				"postgres": `JOIN "employee_employee_id_has_employee_employee_id" ON "employee_employee_id_has_employee_employee_id"."employee_id" = "employee"."id" ` +
+					`JOIN "employee" AS "employee_id" ON "employee_employee_id_has_employee_employee_id"."employee_id_2" = "employee_id"."id"`,
+				"sqlite": `JOIN "employee_employee_id_has_employee_employee_id" ON "employee_employee_id_has_employee_employee_id"."employee_id" = "employee"."id" ` +
+					`JOIN "employee" AS "employee_id" ON "employee_employee_id_has_employee_employee_id"."employee_id_2" = "employee_id"."id"`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		for _, d := range []Dialect{MySQLDialect, PostgresDialect, SQLiteDialect} {
+			t.Run(test.name+"/"+d.Name(), func(t *testing.T) {
+				relation := test.relation
+				var (
+					joins []JoinClause
+					err   error
+				)
+				if test.reverse {
+					joins, err = relation.ReverseJoinClauses(test.from)
+				} else {
+					joins, err = relation.JoinClauses(test.from)
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := joinSQL(d, joins); got != test.want[d.Name()] {
+					t.Errorf("got  %s\nwant %s", got, test.want[d.Name()])
+				}
+			})
+		}
+	}
+}
+
+func TestJoinClausesUnknownRelation(t *testing.T) {
+	relation := TableRelation{Subject: "user", Object: "post", Relation: "has_some"}
+	if _, err := relation.JoinClauses("user"); err == nil {
+		t.Error("expected an error for an unknown relation")
+	}
+	if _, err := relation.ReverseJoinClauses("post"); err == nil {
+		t.Error("expected an error for an unknown relation")
+	}
+}
+
+func TestWhereOperators(t *testing.T) {
+	tests := []struct {
+		operator Operator
+		value    interface{}
+		want     string
+		args     int
+	}{
+		{OpEqual, 1, `SELECT "user".* FROM "user" WHERE "user"."age" = $1`, 1},
+		{OpNotEqual, 1, `SELECT "user".* FROM "user" WHERE "user"."age" <> $1`, 1},
+		{OpGreaterOrEqual, 1, `SELECT "user".* FROM "user" WHERE "user"."age" >= $1`, 1},
+		{OpLike, "a%", `SELECT "user".* FROM "user" WHERE "user"."age" LIKE $1`, 1},
+		{OpIn, []interface{}{1, 2}, `SELECT "user".* FROM "user" WHERE "user"."age" IN ($1, $2)`, 2},
+		{OpIsNull, nil, `SELECT "user".* FROM "user" WHERE "user"."age" IS NULL`, 0},
+	}
+	for _, test := range tests {
+		statement, args, err := NewSelectQuery(PostgresDialect, "user", "").Where("user.age", test.operator, test.value).Build()
+		if err != nil {
+			t.Errorf("%s: %v", test.operator, err)
+			continue
+		}
+		if statement != test.want || len(args) != test.args {
+			t.Errorf("%s: got %s with %d arguments", test.operator, statement, len(args))
+		}
+	}
+}
+
+func TestWhereRejectsUnknownOperators(t *testing.T) {
+	for _, operator := range []Operator{"", "= 1 OR 1 =", "; DROP TABLE user; --", "like"} {
+		if _, _, err := NewSelectQuery(SQLiteDialect, "user", "").Where("user.name", operator, "x").Build(); err == nil {
+			t.Errorf("select accepted operator %q", operator)
+		}
+		if _, _, err := NewSelectQuery(SQLiteDialect, "user", "").Where("user.name", operator, "x").CountQuery(); err == nil {
+			t.Errorf("count accepted operator %q", operator)
+		}
+		if _, _, err := NewUpdateQuery(SQLiteDialect, "user").Set("name", "y").Where("name", operator, "x").Build(); err == nil {
+			t.Errorf("update accepted operator %q", operator)
+		}
+		if _, _, err := NewDeleteQuery(SQLiteDialect, "user").Where("name", operator, "x").Build(); err == nil {
+			t.Errorf("delete accepted operator %q", operator)
+		}
+	}
+}
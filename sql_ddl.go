@@ -0,0 +1,402 @@
+package api2go
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var dataTypeRegex = regexp.MustCompile(`^\s*([a-zA-Z ]+?)\s*(\(([^)]*)\))?\s*$`)
+
+// columnType translates ColumnInfo.DataType into the type name of the dialect.
+// Types which are not known are written as they are.
+func columnType(d Dialect, column ColumnInfo) string {
+	dataType := column.DataType
+	if dataType == "" {
+		dataType = "varchar(255)"
+	}
+
+	matches := dataTypeRegex.FindStringSubmatch(dataType)
+	if matches == nil {
+		return dataType
+	}
+	base := strings.ToLower(matches[1])
+	size := matches[3]
+	sized := func(name string) string {
+		if size == "" {
+			return name
+		}
+		return name + "(" + size + ")"
+	}
+
+	switch d.Name() {
+	case "postgres":
+		switch base {
+		case "int", "integer", "mediumint", "smallint", "tinyint":
+			if column.IsAutoIncrement {
+				return "SERIAL"
+			}
+			return "INTEGER"
+		case "bigint":
+			if column.IsAutoIncrement {
+				return "BIGSERIAL"
+			}
+			return "BIGINT"
+		case "bool", "boolean":
+			return "BOOLEAN"
+		case "datetime", "timestamp":
+			return "TIMESTAMP"
+		case "float", "double", "real":
+			return "DOUBLE PRECISION"
+		case "blob", "binary", "varbinary", "longblob":
+			return "BYTEA"
+		case "json":
+			return "JSONB"
+		case "longtext", "mediumtext":
+			return "TEXT"
+		}
+	case "mysql":
+		switch base {
+		case "int", "integer":
+			return "INT"
+		case "bool", "boolean":
+			return "BOOLEAN"
+		case "uuid":
+			return "CHAR(36)"
+		}
+	case "sqlite":
+		switch base {
+		case "int", "integer", "bigint", "mediumint", "smallint", "tinyint":
+			return "INTEGER"
+		case "bool", "boolean":
+			return "BOOLEAN"
+		case "datetime", "timestamp":
+			return "TIMESTAMP"
+		case "float", "double", "real":
+			return "REAL"
+		case "json", "longtext", "mediumtext", "uuid":
+			return "TEXT"
+		}
+	}
+
+	return strings.ToUpper(sized(base))
+}
+
+func columnDefinition(d Dialect, column ColumnInfo, inlinePrimaryKey bool) string {
+	parts := []string{d.QuoteIdentifier(column.ColumnName), columnType(d, column)}
+
+	if !column.IsNullable || column.IsPrimaryKey {
+		parts = append(parts, "NOT NULL")
+	}
+	if column.DefaultValue != "" && !column.IsAutoIncrement {
+		parts = append(parts, "DEFAULT "+column.DefaultValue)
+	}
+	if column.IsAutoIncrement && d.Name() == "mysql" {
+		parts = append(parts, "AUTO_INCREMENT")
+	}
+	if inlinePrimaryKey {
+		parts = append(parts, "PRIMARY KEY")
+		if column.IsAutoIncrement && d.Name() == "sqlite" {
+			parts = append(parts, "AUTOINCREMENT")
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func foreignKeyConstraint(d Dialect, column, table, key string) string {
+	return fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+		d.QuoteIdentifier(column), d.QuoteIdentifier(table), d.QuoteIdentifier(key))
+}
+
+func indexStatement(d Dialect, table string, column string, unique bool) string {
+	name := table + "_" + column + "_index"
+	keyword := "CREATE INDEX "
+	if unique {
+		name = table + "_" + column + "_unique"
+		keyword = "CREATE UNIQUE INDEX "
+	}
+	return keyword + d.QuoteIdentifier(name) + " ON " + d.QuoteIdentifier(table) + " (" + d.QuoteIdentifier(column) + ")"
+}
+
+// idColumns maps the tables of models to their `id` column
+func idColumns(models []Api2GoModel) map[string]ColumnInfo {
+	result := make(map[string]ColumnInfo)
+	for _, model := range models {
+		for _, column := range model.GetColumns() {
+			if column.ColumnName == "id" {
+				result[model.GetTableName()] = column
+			}
+		}
+	}
+	return result
+}
+
+// referenceColumnType returns the data type of a column holding the `id` of
+// a row of table. Tables missing in ids are expected to have an integer id.
+func referenceColumnType(table string, ids map[string]ColumnInfo) string {
+	if id, ok := ids[table]; ok && id.DataType != "" {
+		return id.DataType
+	}
+	return "integer"
+}
+
+// relationColumns returns the foreign key columns the to-one relations of table
+// need but which are not part of columns already
+func relationColumns(table string, columns []ColumnInfo, relations []TableRelation, ids map[string]ColumnInfo) []ColumnInfo {
+	existing := make(map[string]bool)
+	for _, column := range columns {
+		existing[column.ColumnName] = true
+	}
+
+	result := make([]ColumnInfo, 0)
+	for _, relation := range relations {
+		if relation.GetSubject() != table {
+			continue
+		}
+		if relation.GetRelation() != "has_one" && relation.GetRelation() != "belongs_to" {
+			continue
+		}
+		if existing[relation.GetObjectName()] {
+			continue
+		}
+		existing[relation.GetObjectName()] = true
+		result = append(result, ColumnInfo{
+			Name:         relation.GetObjectName(),
+			ColumnName:   relation.GetObjectName(),
+			DataType:     referenceColumnType(relation.GetObject(), ids),
+			IsNullable:   relation.GetRelation() == "has_one",
+			IsIndexed:    true,
+			IsForeignKey: true,
+			ForeignKeyData: ForeignKeyData{
+				DataSource: "self",
+				Namespace:  relation.GetObject(),
+				KeyName:    "id",
+			},
+		})
+	}
+	return result
+}
+
+// CreateTableDDL returns the CREATE TABLE statement for table followed by the
+// CREATE INDEX statements for its unique (IsUnique) and secondary (IsIndexed) columns.
+//
+// ColumnInfo.DefaultValue is written as a SQL expression, quote string literals
+// in it yourself. Foreign keys are added for columns with IsForeignKey set whose
+// ForeignKeyData points to the same data source.
+// Foreign key columns needed by has_one and belongs_to relations from relations
+// are added when they are not present in columns. They get the type of the id
+// column of the referenced model in referenced, or an integer type.
+func CreateTableDDL(d Dialect, table string, columns []ColumnInfo, relations []TableRelation, referenced ...Api2GoModel) []string {
+	ids := idColumns(referenced)
+	for _, column := range columns {
+		if column.ColumnName == "id" {
+			ids[table] = column
+		}
+	}
+	columns = append(append([]ColumnInfo{}, columns...), relationColumns(table, columns, relations, ids)...)
+
+	primaryKeys := make([]string, 0)
+	for _, column := range columns {
+		if column.IsPrimaryKey {
+			primaryKeys = append(primaryKeys, column.ColumnName)
+		}
+	}
+
+	definitions := make([]string, 0, len(columns))
+	constraints := make([]string, 0)
+	indexes := make([]string, 0)
+
+	for _, column := range columns {
+		definitions = append(definitions, columnDefinition(d, column, column.IsPrimaryKey && len(primaryKeys) == 1))
+
+		if column.IsForeignKey && column.ForeignKeyData.Namespace != "" &&
+			(column.ForeignKeyData.DataSource == "" || column.ForeignKeyData.DataSource == "self") {
+			constraints = append(constraints, foreignKeyConstraint(d, column.ColumnName,
+				column.ForeignKeyData.Namespace, column.ForeignKeyData.KeyName))
+		}
+
+		if column.IsPrimaryKey {
+			continue
+		}
+		if column.IsUnique {
+			indexes = append(indexes, indexStatement(d, table, column.ColumnName, true))
+		} else if column.IsIndexed {
+			indexes = append(indexes, indexStatement(d, table, column.ColumnName, false))
+		}
+	}
+
+	if len(primaryKeys) > 1 {
+		quoted := make([]string, len(primaryKeys))
+		for i, key := range primaryKeys {
+			quoted[i] = d.QuoteIdentifier(key)
+		}
+		constraints = append([]string{"PRIMARY KEY (" + strings.Join(quoted, ", ") + ")"}, constraints...)
+	}
+
+	create := "CREATE TABLE " + d.QuoteIdentifier(table) + " (\n  " +
+		strings.Join(append(definitions, constraints...), ",\n  ") + "\n)"
+
+	return append([]string{create}, indexes...)
+}
+
+// CreateJoinTableDDL returns the statements creating the join table of a has_many
+// or has_many_and_belongs_to_many relation, named by GetJoinTableName, with the
+// key columns named by GetJoinColumnNames.
+// Other relations do not need a join table and return no statements.
+// The key columns get the type of the id columns of the models in referenced,
+// see CreateTableDDL.
+func CreateJoinTableDDL(d Dialect, relation TableRelation, referenced ...Api2GoModel) []string {
+	if !relation.Relation.ToMany() {
+		return []string{}
+	}
+
+	ids := idColumns(referenced)
+	table := relation.GetJoinTableName()
+	subjectColumn, objectColumn := relation.GetJoinColumnNames()
+	columns := []ColumnInfo{
+		{ColumnName: "id", DataType: "integer", IsPrimaryKey: true, IsAutoIncrement: true},
+		{
+			ColumnName:     subjectColumn,
+			DataType:       referenceColumnType(relation.GetSubject(), ids),
+			IsIndexed:      true,
+			IsForeignKey:   true,
+			ForeignKeyData: ForeignKeyData{DataSource: "self", Namespace: relation.GetSubject(), KeyName: "id"},
+		},
+		{
+			ColumnName:     objectColumn,
+			DataType:       referenceColumnType(relation.GetObject(), ids),
+			IsIndexed:      true,
+			IsForeignKey:   true,
+			ForeignKeyData: ForeignKeyData{DataSource: "self", Namespace: relation.GetObject(), KeyName: "id"},
+		},
+	}
+
+	return append(CreateTableDDL(d, table, columns, nil), fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s, %s)",
+		d.QuoteIdentifier(table+"_unique"), d.QuoteIdentifier(table),
+		d.QuoteIdentifier(subjectColumn), d.QuoteIdentifier(objectColumn)))
+}
+
+// AuditColumns returns the columns of the `_audit` companion table of a table,
+// see Api2GoModel.GetAuditModel. Audit rows keep every version of a row, so all
+// unique, index and foreign key constraints are dropped and every column except
+// the primary key becomes nullable.
+func AuditColumns(columns []ColumnInfo) []ColumnInfo {
+	result := make([]ColumnInfo, 0, len(columns))
+	for _, column := range columns {
+		if !column.IsPrimaryKey {
+			column.IsNullable = true
+		}
+		column.IsUnique = false
+		column.IsIndexed = false
+		column.IsForeignKey = false
+		result = append(result, column)
+	}
+	return result
+}
+
+// CreateAuditTableDDL returns the statements creating the `_audit` table for table
+func CreateAuditTableDDL(d Dialect, table string, columns []ColumnInfo) []string {
+	return CreateTableDDL(d, table+"_audit", AuditColumns(columns), nil)
+}
+
+// SchemaDDL returns the statements creating all tables of the given models, then
+// their join tables, then their audit tables. Join tables shared by both sides
+// of a relation are only created once.
+func SchemaDDL(d Dialect, models ...Api2GoModel) []string {
+	statements := make([]string, 0)
+	joinStatements := make([]string, 0)
+	auditStatements := make([]string, 0)
+	joinTables := make(map[string]bool)
+
+	for _, model := range models {
+		statements = append(statements, CreateTableDDL(d, model.GetTableName(), model.GetColumns(), model.GetRelations(), models...)...)
+		auditStatements = append(auditStatements, CreateAuditTableDDL(d, model.GetTableName(), model.GetColumns())...)
+
+		for _, relation := range model.GetRelations() {
+			name := relation.GetJoinTableName()
+			if joinTables[name] {
+				continue
+			}
+			joins := CreateJoinTableDDL(d, relation, models...)
+			if len(joins) > 0 {
+				joinTables[name] = true
+				joinStatements = append(joinStatements, joins...)
+			}
+		}
+	}
+
+	statements = append(statements, joinStatements...)
+	return append(statements, auditStatements...)
+}
+
+// MigrationDDL returns the statements migrating table from the columns in `from`
+// to the columns in `to`. Columns are matched by ColumnName: new columns are
+// added, removed columns are dropped and index changes are applied. Changes to
+// the type of an existing column are not migrated.
+// New columns which are neither nullable nor have a DefaultValue are added as
+// nullable, the rows already in the table would have no value for them.
+func MigrationDDL(d Dialect, table string, from, to []ColumnInfo) []string {
+	old := make(map[string]ColumnInfo)
+	for _, column := range from {
+		old[column.ColumnName] = column
+	}
+	current := make(map[string]ColumnInfo)
+	for _, column := range to {
+		current[column.ColumnName] = column
+	}
+
+	statements := make([]string, 0)
+	quotedTable := d.QuoteIdentifier(table)
+
+	for _, column := range to {
+		previous, ok := old[column.ColumnName]
+		if !ok {
+			added := column
+			if added.DefaultValue == "" {
+				added.IsNullable = true
+			}
+			statements = append(statements, "ALTER TABLE "+quotedTable+" ADD COLUMN "+columnDefinition(d, added, false))
+			if column.IsUnique {
+				statements = append(statements, indexStatement(d, table, column.ColumnName, true))
+			} else if column.IsIndexed {
+				statements = append(statements, indexStatement(d, table, column.ColumnName, false))
+			}
+			continue
+		}
+
+		if previous.IsUnique != column.IsUnique || previous.IsIndexed != column.IsIndexed {
+			if previous.IsUnique {
+				statements = append(statements, dropIndexStatement(d, table, table+"_"+column.ColumnName+"_unique"))
+			} else if previous.IsIndexed {
+				statements = append(statements, dropIndexStatement(d, table, table+"_"+column.ColumnName+"_index"))
+			}
+			if column.IsUnique {
+				statements = append(statements, indexStatement(d, table, column.ColumnName, true))
+			} else if column.IsIndexed {
+				statements = append(statements, indexStatement(d, table, column.ColumnName, false))
+			}
+		}
+	}
+
+	removed := make([]string, 0)
+	for name := range old {
+		if _, ok := current[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		statements = append(statements, "ALTER TABLE "+quotedTable+" DROP COLUMN "+d.QuoteIdentifier(name))
+	}
+
+	return statements
+}
+
+func dropIndexStatement(d Dialect, table, name string) string {
+	if d.Name() == "mysql" {
+		return "DROP INDEX " + d.QuoteIdentifier(name) + " ON " + d.QuoteIdentifier(table)
+	}
+	return "DROP INDEX " + d.QuoteIdentifier(name)
+}
@@ -0,0 +1,201 @@
+package api2go
+
+import (
+	"reflect"
+	"testing"
+)
+
+var ddlDialects = []Dialect{MySQLDialect, PostgresDialect, SQLiteDialect}
+
+func checkStatements(t *testing.T, got []string, want []string) {
+	t.Helper()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got  %q\nwant %q", got, want)
+	}
+}
+
+func ddlUser() Api2GoModel {
+	return NewApi2GoModel("user", []ColumnInfo{
+		{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+		{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+		{ColumnName: "email", DataType: "varchar(100)", IsIndexed: true},
+		{ColumnName: "active", DataType: "bool", DefaultValue: "true"},
+	}, 0, nil)
+}
+
+func TestCreateTableDDL(t *testing.T) {
+	want := map[string][]string{
+		"mysql": {
+			"CREATE TABLE `user` (\n  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n  `reference_id` VARCHAR(64) NOT NULL,\n" +
+				"  `email` VARCHAR(100) NOT NULL,\n  `active` BOOLEAN NOT NULL DEFAULT true\n)",
+			"CREATE UNIQUE INDEX `user_reference_id_unique` ON `user` (`reference_id`)",
+			"CREATE INDEX `user_email_index` ON `user` (`email`)",
+		},
+		"postgres": {
+			"CREATE TABLE \"user\" (\n  \"id\" SERIAL NOT NULL PRIMARY KEY,\n  \"reference_id\" VARCHAR(64) NOT NULL,\n" +
+				"  \"email\" VARCHAR(100) NOT NULL,\n  \"active\" BOOLEAN NOT NULL DEFAULT true\n)",
+			`CREATE UNIQUE INDEX "user_reference_id_unique" ON "user" ("reference_id")`,
+			`CREATE INDEX "user_email_index" ON "user" ("email")`,
+		},
+		"sqlite": {
+			"CREATE TABLE \"user\" (\n  \"id\" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,\n  \"reference_id\" VARCHAR(64) NOT NULL,\n" +
+				"  \"email\" VARCHAR(100) NOT NULL,\n  \"active\" BOOLEAN NOT NULL DEFAULT true\n)",
+			`CREATE UNIQUE INDEX "user_reference_id_unique" ON "user" ("reference_id")`,
+			`CREATE INDEX "user_email_index" ON "user" ("email")`,
+		},
+	}
+	for _, d := range ddlDialects {
+		t.Run(d.Name(), func(t *testing.T) {
+			checkStatements(t, CreateTableDDL(d, "user", ddlUser().GetColumns(), nil), want[d.Name()])
+		})
+	}
+}
+
+func TestCreateTableDDLRelationColumns(t *testing.T) {
+	columns := []ColumnInfo{
+		{ColumnName: "id", DataType: "bigint", IsPrimaryKey: true, IsAutoIncrement: true},
+		{ColumnName: "title", DataType: "varchar(200)", IsNullable: true},
+	}
+	relations := []TableRelation{NewTableRelation("post", "belongs_to", "user")}
+	want := map[string][]string{
+		"mysql": {
+			"CREATE TABLE `post` (\n  `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n  `title` VARCHAR(200),\n" +
+				"  `user_id` INT NOT NULL,\n  FOREIGN KEY (`user_id`) REFERENCES `user` (`id`)\n)",
+			"CREATE INDEX `post_user_id_index` ON `post` (`user_id`)",
+		},
+		"postgres": {
+			"CREATE TABLE \"post\" (\n  \"id\" BIGSERIAL NOT NULL PRIMARY KEY,\n  \"title\" VARCHAR(200),\n" +
+				"  \"user_id\" INTEGER NOT NULL,\n  FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\")\n)",
+			`CREATE INDEX "post_user_id_index" ON "post" ("user_id")`,
+		},
+		"sqlite": {
+			"CREATE TABLE \"post\" (\n  \"id\" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,\n  \"title\" VARCHAR(200),\n" +
+				"  \"user_id\" INTEGER NOT NULL,\n  FOREIGN KEY (\"user_id\") REFERENCES \"user\" (\"id\")\n)",
+			`CREATE INDEX "post_user_id_index" ON "post" ("user_id")`,
+		},
+	}
+	for _, d := range ddlDialects {
+		t.Run(d.Name(), func(t *testing.T) {
+			checkStatements(t, CreateTableDDL(d, "post", columns, relations, ddlUser()), want[d.Name()])
+		})
+	}
+}
+
+func TestCreateJoinTableDDL(t *testing.T) {
+	tests := []struct {
+		name     string
+		relation TableRelation
+		want     map[string][]string
+	}{
+		{
+			name:     "many_to_many",
+			relation: NewTableRelation("post", "has_many_and_belongs_to_many", "tag"),
+			want: map[string][]string{
+				"mysql": {
+					"CREATE TABLE `post_post_id_has_tag_tag_id` (\n  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+						"  `post_id` INT NOT NULL,\n  `tag_id` INT NOT NULL,\n" +
+						"  FOREIGN KEY (`post_id`) REFERENCES `post` (`id`),\n  FOREIGN KEY (`tag_id`) REFERENCES `tag` (`id`)\n)",
+					"CREATE INDEX `post_post_id_has_tag_tag_id_post_id_index` ON `post_post_id_has_tag_tag_id` (`post_id`)",
+					"CREATE INDEX `post_post_id_has_tag_tag_id_tag_id_index` ON `post_post_id_has_tag_tag_id` (`tag_id`)",
+					"CREATE UNIQUE INDEX `post_post_id_has_tag_tag_id_unique` ON `post_post_id_has_tag_tag_id` (`post_id`, `tag_id`)",
+				},
+				"postgres": {
+					"CREATE TABLE \"post_post_id_has_tag_tag_id\" (\n  \"id\" SERIAL NOT NULL PRIMARY KEY,\n" +
+						"  \"post_id\" INTEGER NOT NULL,\n  \"tag_id\" INTEGER NOT NULL,\n" +
+						"  FOREIGN KEY (\"post_id\") REFERENCES \"post\" (\"id\"),\n  FOREIGN KEY (\"tag_id\") REFERENCES \"tag\" (\"id\")\n)",
+					`CREATE INDEX "post_post_id_has_tag_tag_id_post_id_index" ON "post_post_id_has_tag_tag_id" ("post_id")`,
+					`CREATE INDEX "post_post_id_has_tag_tag_id_tag_id_index" ON "post_post_id_has_tag_tag_id" ("tag_id")`,
+					`CREATE UNIQUE INDEX "post_post_id_has_tag_tag_id_unique" ON "post_post_id_has_tag_tag_id" ("post_id", "tag_id")`,
+				},
+				"sqlite": {
+					"CREATE TABLE \"post_post_id_has_tag_tag_id\" (\n  \"id\" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,\n" +
+						"  \"post_id\" INTEGER NOT NULL,\n  \"tag_id\" INTEGER NOT NULL,\n" +
+						"  FOREIGN KEY (\"post_id\") REFERENCES \"post\" (\"id\"),\n  FOREIGN KEY (\"tag_id\") REFERENCES \"tag\" (\"id\")\n)",
+					`CREATE INDEX "post_post_id_has_tag_tag_id_post_id_index" ON "post_post_id_has_tag_tag_id" ("post_id")`,
+					`CREATE INDEX "post_post_id_has_tag_tag_id_tag_id_index" ON "post_post_id_has_tag_tag_id" ("tag_id")`,
+					`CREATE UNIQUE INDEX "post_post_id_has_tag_tag_id_unique" ON "post_post_id_has_tag_tag_id" ("post_id", "tag_id")`,
+				},
+			},
+		},
+		{
+			name:     "self many_to_many with default names",
+			relation: NewTableRelation("employee", "has_many_and_belongs_to_many", "employee"),
+			want: map[string][]string{
+				"mysql": {
+					"CREATE TABLE `employee_employee_id_has_employee_employee_id` (\n  `id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,\n" +
+						"  `employee_id` INT NOT NULL,\n  `employee_id_2` INT NOT NULL,\n" +
+						"  FOREIGN KEY (`employee_id`) REFERENCES `employee` (`id`),\n  FOREIGN KEY (`employee_id_2`) REFERENCES `employee` (`id`)\n)",
+					"CREATE INDEX `employee_employee_id_has_employee_employee_id_employee_id_index` ON `employee_employee_id_has_employee_employee_id` (`employee_id`)",
+					"CREATE INDEX `employee_employee_id_has_employee_employee_id_employee_id_2_index` ON `employee_employee_id_has_employee_employee_id` (`employee_id_2`)",
+					"CREATE UNIQUE INDEX `employee_employee_id_has_employee_employee_id_unique` ON `employee_employee_id_has_employee_employee_id` (`employee_id`, `employee_id_2`)",
+				},
+				"postgres": {
+					"CREATE TABLE \"employee_employee_id_has_employee_employee_id\" (\n  \"id\" SERIAL NOT NULL PRIMARY KEY,\n" +
+						"  \"employee_id\" INTEGER NOT NULL,\n  \"employee_id_2\" INTEGER NOT NULL,\n" +
+						"  FOREIGN KEY (\"employee_id\") REFERENCES \"employee\" (\"id\"),\n  FOREIGN KEY (\"employee_id_2\") REFERENCES \"employee\" (\"id\")\n)",
+					`CREATE INDEX "employee_employee_id_has_employee_employee_id_employee_id_index" ON "employee_employee_id_has_employee_employee_id" ("employee_id")`,
+					`CREATE INDEX "employee_employee_id_has_employee_employee_id_employee_id_2_index" ON "employee_employee_id_has_employee_employee_id" ("employee_id_2")`,
+					`CREATE UNIQUE INDEX "employee_employee_id_has_employee_employee_id_unique" ON "employee_employee_id_has_employee_employee_id" ("employee_id", "employee_id_2")`,
+				},
+				"sqlite": {
+					"CREATE TABLE \"employee_employee_id_has_employee_employee_id\" (\n  \"id\" INTEGER NOT NULL PRIMARY KEY AUTOINCREMENT,\n" +
+						"  \"employee_id\" INTEGER NOT NULL,\n  \"employee_id_2\" INTEGER NOT NULL,\n" +
+						"  FOREIGN KEY (\"employee_id\") REFERENCES \"employee\" (\"id\"),\n  FOREIGN KEY (\"employee_id_2\") REFERENCES \"employee\" (\"id\")\n)",
+					`CREATE INDEX "employee_employee_id_has_employee_employee_id_employee_id_index" ON "employee_employee_id_has_employee_employee_id" ("employee_id")`,
+					`CREATE INDEX "employee_employee_id_has_employee_employee_id_employee_id_2_index" ON "employee_employee_id_has_employee_employee_id" ("employee_id_2")`,
+					`CREATE UNIQUE INDEX "employee_employee_id_has_employee_employee_id_unique" ON "employee_employee_id_has_employee_employee_id" ("employee_id", "employee_id_2")`,
+				},
+			},
+		},
+	}
+	for _, test := range tests {
+		for _, d := range ddlDialects {
+			t.Run(test.name+"/"+d.Name(), func(t *testing.T) {
+				checkStatements(t, CreateJoinTableDDL(d, test.relation), test.want[d.Name()])
+			})
+		}
+	}
+
+	if statements := CreateJoinTableDDL(SQLiteDialect, NewTableRelation("post", "belongs_to", "user")); len(statements) != 0 {
+		t.Errorf("expected no join table for belongs_to, got %q", statements)
+	}
+}
+
+func TestMigrationDDL(t *testing.T) {
+	to := []ColumnInfo{
+		{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+		{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+		{ColumnName: "email", DataType: "varchar(100)", IsUnique: true},
+		// not nullable without a default, added as nullable
+		{ColumnName: "name", DataType: "varchar(100)"},
+		{ColumnName: "score", DataType: "int(11)", DefaultValue: "0"},
+	}
+	want := map[string][]string{
+		"mysql": {
+			"DROP INDEX `user_email_index` ON `user`",
+			"CREATE UNIQUE INDEX `user_email_unique` ON `user` (`email`)",
+			"ALTER TABLE `user` ADD COLUMN `name` VARCHAR(100)",
+			"ALTER TABLE `user` ADD COLUMN `score` INT NOT NULL DEFAULT 0",
+			"ALTER TABLE `user` DROP COLUMN `active`",
+		},
+		"postgres": {
+			`DROP INDEX "user_email_index"`,
+			`CREATE UNIQUE INDEX "user_email_unique" ON "user" ("email")`,
+			`ALTER TABLE "user" ADD COLUMN "name" VARCHAR(100)`,
+			`ALTER TABLE "user" ADD COLUMN "score" INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE "user" DROP COLUMN "active"`,
+		},
+		"sqlite": {
+			`DROP INDEX "user_email_index"`,
+			`CREATE UNIQUE INDEX "user_email_unique" ON "user" ("email")`,
+			`ALTER TABLE "user" ADD COLUMN "name" VARCHAR(100)`,
+			`ALTER TABLE "user" ADD COLUMN "score" INTEGER NOT NULL DEFAULT 0`,
+			`ALTER TABLE "user" DROP COLUMN "active"`,
+		},
+	}
+	for _, d := range ddlDialects {
+		t.Run(d.Name(), func(t *testing.T) {
+			checkStatements(t, MigrationDDL(d, "user", ddlUser().GetColumns(), to), want[d.Name()])
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package api2go
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Dialect describes how SQL generated by api2go is written for a specific
+// database. Identifiers are quoted and parameters numbered the way the
+// database driver expects them.
+type Dialect interface {
+	// Name returns the name of the dialect, e.g. "postgres"
+	Name() string
+	// QuoteIdentifier quotes a single table, alias or column name
+	QuoteIdentifier(name string) string
+	// Placeholder returns the bind parameter for the n-th (1 based) argument
+	Placeholder(n int) string
+}
+
+// The dialects supported out of the box.
+var (
+	PostgresDialect Dialect = postgresDialect{}
+	MySQLDialect    Dialect = mysqlDialect{}
+	SQLiteDialect   Dialect = sqliteDialect{}
+)
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) QuoteIdentifier(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+func (mysqlDialect) Placeholder(n int) string {
+	return "?"
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string {
+	return "sqlite"
+}
+
+func (sqliteDialect) QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+func (sqliteDialect) Placeholder(n int) string {
+	return "?"
+}
+
+// quoteColumn quotes a possibly qualified column like `alias.column`.
+// The wildcard `*` is never quoted.
+func quoteColumn(d Dialect, column string) string {
+	parts := strings.Split(column, ".")
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = d.QuoteIdentifier(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,899 @@
+package api2go
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// SQLResource is a generic data source for Api2GoModel resources stored in a
+// database/sql database. It implements CRUD, FindAll and PaginatedFindAll and
+// writes the relationship edits collected by the EditToManyRelations methods
+// of Api2GoModel.
+//
+// Tables are expected in the layout generated by SchemaDDL: an auto increment
+// `id` primary key, the resource id in `reference_id`, foreign key columns
+// holding the `id` of the referenced row, join tables for to-many relations
+// and an `_audit` companion table. If the model has a `version` column it is
+// set to 1 on create and incremented on every update, the previous state of
+// the row is written to the audit table before. Updates of a row changed by
+// someone else since it was read fail with 409 Conflict: the version sent in
+// the document is compared with the stored one.
+type SQLResource struct {
+	db      *sql.DB
+	dialect Dialect
+	model   Api2GoModel
+}
+
+// NewSQLResource returns a data source for the table of model
+func NewSQLResource(db *sql.DB, dialect Dialect, model Api2GoModel) *SQLResource {
+	return &SQLResource{db: db, dialect: dialect, model: model}
+}
+
+// Compile time checks
+var (
+	_ CRUD                   = &SQLResource{}
+	_ FindAll                = &SQLResource{}
+	_ PaginatedFindAll       = &SQLResource{}
+	_ ObjectInitializer      = &SQLResource{}
+	_ RelatedFinder          = &SQLResource{}
+	_ PaginatedRelatedFinder = &SQLResource{}
+)
+
+// InitializeObject sets name, columns and relations of the model on objects
+// created for unmarshalling, so relationships in the request can be resolved
+func (s *SQLResource) InitializeObject(obj interface{}) {
+	if model, ok := obj.(*Api2GoModel); ok {
+		model.typeName = s.model.GetTableName()
+		model.columns = s.model.GetColumns()
+		model.relations = s.model.GetRelations()
+		model.defaultPermission = s.model.GetDefaultPermission()
+	}
+}
+
+type sqlQueryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// toManyLink describes one side of a to-many relation of the table
+type toManyLink struct {
+	relation   TableRelation
+	key        string
+	selfColumn string
+	otherTable string
+	otherKey   string
+}
+
+func (s *SQLResource) table() string {
+	return s.model.GetTableName()
+}
+
+func (s *SQLResource) hasColumn(name string) bool {
+	_, ok := s.model.GetColumnMap()[name]
+	return ok
+}
+
+// known reports if name can be used to sort or filter
+func (s *SQLResource) known(name string) bool {
+	column, ok := s.model.GetColumnMap()[name]
+	return ok && name != "id" && !column.ExcludeFromApi
+}
+
+func (s *SQLResource) toOneRelations() []TableRelation {
+	result := make([]TableRelation, 0)
+	for _, relation := range s.model.GetRelations() {
+		if relation.GetSubject() != s.table() {
+			continue
+		}
+		if relation.GetRelation() == "has_one" || relation.GetRelation() == "belongs_to" {
+			result = append(result, relation)
+		}
+	}
+	return result
+}
+
+func (s *SQLResource) toManyLinks() []toManyLink {
+	result := make([]toManyLink, 0)
+	for _, relation := range s.model.GetRelations() {
+		if !relation.Relation.ToMany() {
+			continue
+		}
+		subjectColumn, objectColumn := relation.GetJoinColumnNames()
+		if relation.GetSubject() == s.table() {
+			result = append(result, toManyLink{
+				relation:   relation,
+				key:        relation.GetObjectName(),
+				selfColumn: subjectColumn,
+				otherTable: relation.GetObject(),
+				otherKey:   objectColumn,
+			})
+		}
+		if relation.GetObject() == s.table() {
+			result = append(result, toManyLink{
+				relation:   relation,
+				key:        relation.GetSubjectName(),
+				selfColumn: objectColumn,
+				otherTable: relation.GetSubject(),
+				otherKey:   subjectColumn,
+			})
+		}
+	}
+	return result
+}
+
+func requestContext(req Request) context.Context {
+	if req.PlainRequest != nil {
+		return req.PlainRequest.Context()
+	}
+	return context.Background()
+}
+
+// selectQuery selects all columns of the table, to-one foreign keys are
+// replaced with the reference_id of the referenced row
+func (s *SQLResource) selectQuery() *SelectQuery {
+	table := s.table()
+	q := NewSelectQuery(s.dialect, table, "")
+
+	toOne := s.toOneRelations()
+	foreignKeys := make(map[string]bool)
+	for _, relation := range toOne {
+		foreignKeys[relation.GetObjectName()] = true
+	}
+
+	q.Columns(table + ".reference_id")
+	for _, column := range s.model.GetColumnNames() {
+		if column == "id" || column == "reference_id" || foreignKeys[column] {
+			continue
+		}
+		q.Columns(table + "." + column)
+	}
+
+	for _, relation := range toOne {
+		joins, err := relation.JoinClauses(table)
+		if err != nil {
+			continue
+		}
+		join := joins[0]
+		join.Type = LeftJoin
+		q.Join(join)
+		q.ColumnAs(join.Alias+".reference_id", relation.GetObjectName())
+	}
+
+	return q
+}
+
+func scanRows(rows *sql.Rows) ([]map[string]interface{}, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if bytes, ok := values[i].([]byte); ok {
+				row[column] = string(bytes)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQLResource) query(ctx context.Context, db sqlQueryer, statement string, args []interface{}) ([]map[string]interface{}, error) {
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanRows(rows)
+}
+
+// loadToManyIDs sets the reference ids of all to-many and reverse to-one
+// relations on row, as expected by Api2GoModel.GetReferencedIDs
+func (s *SQLResource) loadToManyIDs(ctx context.Context, db sqlQueryer, row map[string]interface{}) error {
+	table := s.table()
+	referenceID := row["reference_id"]
+
+	for _, relation := range s.model.GetRelations() {
+		var (
+			q     *SelectQuery
+			joins []JoinClause
+			err   error
+			key   string
+		)
+
+		toMany := relation.Relation.ToMany()
+		if relation.GetObject() == table {
+			// the subject references us, either directly or through the join table
+			q = NewSelectQuery(s.dialect, relation.GetSubject(), "")
+			joins, err = relation.JoinClauses(q.Alias())
+			key = relation.GetSubjectName()
+		} else if relation.GetSubject() == table && toMany {
+			q = NewSelectQuery(s.dialect, relation.GetObject(), "")
+			joins, err = relation.ReverseJoinClauses(q.Alias())
+			key = relation.GetObjectName()
+		} else {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		q.Columns(q.Alias() + ".reference_id").Join(joins...)
+		q.Where(joins[len(joins)-1].Alias+".reference_id", OpEqual, referenceID)
+
+		statement, args, err := q.Build()
+		if err != nil {
+			return err
+		}
+		rows, err := s.query(ctx, db, statement, args)
+		if err != nil {
+			return err
+		}
+
+		ids := make([]string, 0, len(rows))
+		for _, related := range rows {
+			ids = append(ids, fmt.Sprintf("%v", related["reference_id"]))
+		}
+		row[key] = ids
+	}
+
+	return nil
+}
+
+func (s *SQLResource) toModels(ctx context.Context, db sqlQueryer, rows []map[string]interface{}) ([]Api2GoModel, error) {
+	result := make([]Api2GoModel, 0, len(rows))
+	for _, row := range rows {
+		if err := s.loadToManyIDs(ctx, db, row); err != nil {
+			return nil, err
+		}
+		result = append(result, NewApi2GoModelWithData(s.table(), s.model.GetColumns(),
+			s.model.GetDefaultPermission(), s.model.GetRelations(), row))
+	}
+	return result, nil
+}
+
+// internalID returns the `id` of the row of table with the given reference id
+func (s *SQLResource) internalID(ctx context.Context, db sqlQueryer, table string, referenceID interface{}) (interface{}, error) {
+	q := NewSelectQuery(s.dialect, table, "").Columns(table+".id").Where(table+".reference_id", OpEqual, fmt.Sprintf("%v", referenceID))
+	statement, args, err := q.Build()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.query(ctx, db, statement, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, NewHTTPError(sql.ErrNoRows, fmt.Sprintf("%s with id %v not found", table, referenceID), http.StatusNotFound)
+	}
+	return rows[0]["id"], nil
+}
+
+// relatedIDs returns the `id`s of rows related to the parent resource through the
+// relation called relationName on the parent's side. ok is false if there is no
+// such relation.
+func (s *SQLResource) relatedIDs(ctx context.Context, parentType, parentID, relationName string) (ids []interface{}, ok bool, err error) {
+	table := s.table()
+	q := NewSelectQuery(s.dialect, table, "")
+
+	var joins []JoinClause
+	for _, relation := range s.model.GetRelations() {
+		if relation.GetSubject() == parentType && relation.GetObject() == table && relation.GetObjectName() == relationName {
+			joins, err = relation.ReverseJoinClauses(q.Alias())
+			ok = true
+			break
+		}
+		if relation.GetObject() == parentType && relation.GetSubject() == table && relation.GetSubjectName() == relationName {
+			joins, err = relation.JoinClauses(q.Alias())
+			ok = true
+			break
+		}
+	}
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+
+	q.Columns(table+".id").Join(joins...).Where(joins[len(joins)-1].Alias+".reference_id", OpEqual, parentID)
+	statement, args, err := q.Build()
+	if err != nil {
+		return nil, true, err
+	}
+	rows, err := s.query(ctx, s.db, statement, args)
+	if err != nil {
+		return nil, true, err
+	}
+
+	ids = make([]interface{}, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row["id"])
+	}
+	return ids, true, nil
+}
+
+// find returns all rows matching the sort, filter and pagination parameters of
+// req, together with the total count of matching rows. If parent is given only
+// rows related to it are returned.
+func (s *SQLResource) find(req Request, parent *relatedParent) (uint, []Api2GoModel, error) {
+	ctx := requestContext(req)
+	table := s.table()
+
+	options, err := parseListOptions(req, s.known)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	q := s.selectQuery()
+	for _, filter := range options.filters {
+		values := make([]interface{}, len(filter.values))
+		for i, value := range filter.values {
+			values[i] = value
+		}
+		if len(values) == 1 {
+			q.Where(table+"."+filter.name, OpEqual, values[0])
+		} else {
+			q.WhereIn(table+"."+filter.name, values)
+		}
+	}
+
+	if parent != nil {
+		ids, found, err := s.relatedIDs(ctx, parent.typeName, parent.id, parent.relation)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !found {
+			return 0, nil, parent.notFound(table)
+		}
+		q.WhereIn(table+".id", ids)
+	}
+
+	countStatement, countArgs, err := q.CountQuery()
+	if err != nil {
+		return 0, nil, err
+	}
+	var count uint
+	if err := s.db.QueryRowContext(ctx, countStatement, countArgs...).Scan(&count); err != nil {
+		return 0, nil, err
+	}
+
+	for _, field := range options.sort {
+		q.OrderBy(table+"."+field.name, field.descending)
+	}
+	q.Limit(options.limit).Offset(options.offset)
+
+	statement, args, err := q.Build()
+	if err != nil {
+		return 0, nil, err
+	}
+	rows, err := s.query(ctx, s.db, statement, args)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	models, err := s.toModels(ctx, s.db, rows)
+	return count, models, err
+}
+
+// FindAll returns all rows matching the sort, filter and page query parameters
+func (s *SQLResource) FindAll(req Request) (Responder, error) {
+	_, models, err := s.find(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// PaginatedFindAll returns one page of rows and the total count
+func (s *SQLResource) PaginatedFindAll(req Request) (uint, Responder, error) {
+	count, models, err := s.find(req, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// FindRelated returns the rows related to the parent resource
+func (s *SQLResource) FindRelated(parentType, parentID, relationName string, req Request) (Responder, error) {
+	_, models, err := s.find(req, &relatedParent{typeName: parentType, id: parentID, relation: relationName})
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+// PaginatedFindRelated returns one page of the rows related to the parent resource
+func (s *SQLResource) PaginatedFindRelated(parentType, parentID, relationName string, req Request) (uint, Responder, error) {
+	count, models, err := s.find(req, &relatedParent{typeName: parentType, id: parentID, relation: relationName})
+	if err != nil {
+		return 0, nil, err
+	}
+	return count, &Response{Res: models, Code: http.StatusOK}, nil
+}
+
+func (s *SQLResource) findOne(ctx context.Context, db sqlQueryer, ID string) (Api2GoModel, error) {
+	q := s.selectQuery()
+	q.Where(s.table()+".reference_id", OpEqual, ID)
+
+	statement, args, err := q.Build()
+	if err != nil {
+		return Api2GoModel{}, err
+	}
+	rows, err := s.query(ctx, db, statement, args)
+	if err != nil {
+		return Api2GoModel{}, err
+	}
+	if len(rows) == 0 {
+		return Api2GoModel{}, NewHTTPError(sql.ErrNoRows, fmt.Sprintf("%s with id %s not found", s.table(), ID), http.StatusNotFound)
+	}
+
+	models, err := s.toModels(ctx, db, rows)
+	if err != nil {
+		return Api2GoModel{}, err
+	}
+	return models[0], nil
+}
+
+// FindOne returns the row with the given reference id
+func (s *SQLResource) FindOne(ID string, req Request) (Responder, error) {
+	model, err := s.findOne(requestContext(req), s.db, ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: model, Code: http.StatusOK}, nil
+}
+
+func toModelPointer(obj interface{}) (*Api2GoModel, error) {
+	switch model := obj.(type) {
+	case Api2GoModel:
+		return &model, nil
+	case *Api2GoModel:
+		return model, nil
+	}
+	return nil, NewHTTPError(nil, fmt.Sprintf("Invalid instance given: %T", obj), http.StatusBadRequest)
+}
+
+// columnValue translates an attribute value for column into the value stored,
+// to-one references are stored as the `id` of the referenced row
+func (s *SQLResource) columnValue(ctx context.Context, db sqlQueryer, column string, value interface{}) (interface{}, error) {
+	for _, relation := range s.toOneRelations() {
+		if relation.GetObjectName() != column {
+			continue
+		}
+		if value == nil || value == "" {
+			return nil, nil
+		}
+		return s.internalID(ctx, db, relation.GetObject(), value)
+	}
+
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		serialized, err := jsonLib.Marshal(value)
+		return string(serialized), err
+	case fmt.Stringer:
+		return fmt.Sprintf("%v", value), nil
+	}
+	return value, nil
+}
+
+// writableColumns returns the columns of the model which can be written from
+// attributes, in the order of the model columns
+func (s *SQLResource) writableColumns() []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, column := range s.model.GetColumnNames() {
+		if column == "id" || column == "reference_id" || column == "version" {
+			continue
+		}
+		seen[column] = true
+		result = append(result, column)
+	}
+	for _, relation := range s.toOneRelations() {
+		if !seen[relation.GetObjectName()] {
+			seen[relation.GetObjectName()] = true
+			result = append(result, relation.GetObjectName())
+		}
+	}
+	return result
+}
+
+func (s *SQLResource) versionConflict(referenceID string) error {
+	conflict := fmt.Errorf("%s with id %s was changed by another request", s.table(), referenceID)
+	return NewHTTPError(conflict, conflict.Error(), http.StatusConflict)
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case string:
+		parsed, _ := strconv.ParseInt(v, 10, 64)
+		return parsed
+	}
+	return 0
+}
+
+func (s *SQLResource) insertJoinRows(ctx context.Context, db sqlQueryer, link toManyLink, selfID interface{}, referenceIDs []string) error {
+	for _, referenceID := range referenceIDs {
+		otherID, err := s.internalID(ctx, db, link.otherTable, referenceID)
+		if err != nil {
+			return err
+		}
+
+		exists := NewSelectQuery(s.dialect, link.relation.GetJoinTableName(), "").
+			Columns(link.relation.GetJoinTableName()+".id").
+			Where(link.relation.GetJoinTableName()+"."+link.selfColumn, OpEqual, selfID).
+			Where(link.relation.GetJoinTableName()+"."+link.otherKey, OpEqual, otherID)
+		statement, args, err := exists.Build()
+		if err != nil {
+			return err
+		}
+		rows, err := s.query(ctx, db, statement, args)
+		if err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			continue
+		}
+
+		statement, args = NewInsertQuery(s.dialect, link.relation.GetJoinTableName()).
+			Set(link.selfColumn, selfID).
+			Set(link.otherKey, otherID).
+			Build()
+		if _, err := db.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLResource) deleteJoinRows(ctx context.Context, db sqlQueryer, link toManyLink, selfID interface{}, referenceIDs []string) error {
+	q := NewDeleteQuery(s.dialect, link.relation.GetJoinTableName()).Where(link.selfColumn, OpEqual, selfID)
+
+	if referenceIDs != nil {
+		otherIDs := make([]interface{}, 0, len(referenceIDs))
+		for _, referenceID := range referenceIDs {
+			otherID, err := s.internalID(ctx, db, link.otherTable, referenceID)
+			if err != nil {
+				return err
+			}
+			otherIDs = append(otherIDs, otherID)
+		}
+		q.WhereIn(link.otherKey, otherIDs)
+	}
+
+	statement, args, err := q.Build()
+	if err != nil {
+		return err
+	}
+	_, err = db.ExecContext(ctx, statement, args...)
+	return err
+}
+
+// referenceIDsOf returns the ids set by SetToManyReferenceIDs, ok is false if
+// the relation was not replaced
+func referenceIDsOf(value interface{}) (ids []string, ok bool) {
+	rows, ok := value.([]map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	ids = make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, fmt.Sprintf("%v", row["id"]))
+	}
+	return ids, true
+}
+
+func (s *SQLResource) inTransaction(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Create inserts a new row and its to-many relations
+func (s *SQLResource) Create(obj interface{}, req Request) (Responder, error) {
+	model, err := toModelPointer(obj)
+	if err != nil {
+		return nil, err
+	}
+	if model.data == nil {
+		model.data = make(map[string]interface{})
+	}
+	if err := model.BeforeCreate(); err != nil {
+		return nil, err
+	}
+	// GetID would return the id from before BeforeCreate for models which were
+	// marked dirty by relationships during unmarshalling
+	referenceID := fmt.Sprintf("%v", model.data["reference_id"])
+	ctx := requestContext(req)
+
+	err = s.inTransaction(ctx, func(tx *sql.Tx) error {
+		insert := NewInsertQuery(s.dialect, s.table()).Set("reference_id", referenceID)
+		if s.hasColumn("version") {
+			insert.Set("version", int64(1))
+		}
+		for _, column := range s.writableColumns() {
+			value, ok := model.data[column]
+			if !ok {
+				continue
+			}
+			stored, err := s.columnValue(ctx, tx, column, value)
+			if err != nil {
+				return err
+			}
+			insert.Set(column, stored)
+		}
+
+		statement, args := insert.Build()
+		if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+			return err
+		}
+
+		selfID, err := s.internalID(ctx, tx, s.table(), referenceID)
+		if err != nil {
+			return err
+		}
+		for _, link := range s.toManyLinks() {
+			if ids, ok := referenceIDsOf(model.data[link.key]); ok {
+				if err := s.insertJoinRows(ctx, tx, link, selfID, ids); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := s.findOne(ctx, s.db, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: created, Code: http.StatusCreated}, nil
+}
+
+// writeAudit stores the state of the row before an update in the audit table
+func (s *SQLResource) writeAudit(ctx context.Context, db sqlQueryer, model Api2GoModel) error {
+	audit := model.GetAuditModel()
+	insert := NewInsertQuery(s.dialect, audit.GetTableName())
+	columns := 0
+	for _, column := range s.model.GetColumnNames() {
+		if column == "id" || column == "reference_id" {
+			continue
+		}
+		value, ok := audit.data[column]
+		if !ok {
+			continue
+		}
+		stored, err := s.columnValue(ctx, db, column, value)
+		if err != nil {
+			return err
+		}
+		insert.Set(column, stored)
+		columns++
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	statement, args := insert.Build()
+	_, err := db.ExecContext(ctx, statement, args...)
+	return err
+}
+
+// Update writes changed attributes and relationships. If the table is
+// versioned, a version in the document must match the stored one, clients
+// send back the version they read to have lost updates rejected.
+func (s *SQLResource) Update(obj interface{}, req Request) (Responder, error) {
+	model, err := toModelPointer(obj)
+	if err != nil {
+		return nil, err
+	}
+	referenceID := model.GetID()
+	ctx := requestContext(req)
+
+	err = s.inTransaction(ctx, func(tx *sql.Tx) error {
+		selfID, err := s.internalID(ctx, tx, s.table(), referenceID)
+		if err != nil {
+			return err
+		}
+
+		versioned := s.hasColumn("version")
+		version := toInt64(model.GetUnmodifiedAttributes()["version"])
+		// the document carries the version the client read
+		if submitted := model.data["version"]; versioned && submitted != nil && toInt64(submitted) != version {
+			return s.versionConflict(referenceID)
+		}
+
+		changes := model.GetChanges()
+		update := NewUpdateQuery(s.dialect, s.table())
+		changed := 0
+		for _, column := range s.writableColumns() {
+			change, ok := changes[column]
+			if !ok {
+				continue
+			}
+			stored, err := s.columnValue(ctx, tx, column, change.NewValue)
+			if err != nil {
+				return err
+			}
+			update.Set(column, stored)
+			changed++
+		}
+
+		for _, link := range s.toManyLinks() {
+			if ids, ok := referenceIDsOf(model.data[link.key]); ok {
+				if err := s.deleteJoinRows(ctx, tx, link, selfID, nil); err != nil {
+					return err
+				}
+				if err := s.insertJoinRows(ctx, tx, link, selfID, ids); err != nil {
+					return err
+				}
+				changed++
+			}
+			if ids, ok := model.AddIncludes[link.key]; ok {
+				if err := s.insertJoinRows(ctx, tx, link, selfID, ids); err != nil {
+					return err
+				}
+				changed++
+			}
+			if ids, ok := model.DeleteIncludes[link.key]; ok {
+				if err := s.deleteJoinRows(ctx, tx, link, selfID, ids); err != nil {
+					return err
+				}
+				changed++
+			}
+		}
+
+		if changed == 0 {
+			return nil
+		}
+
+		update.Where("id", OpEqual, selfID)
+		if versioned {
+			if err := s.writeAudit(ctx, tx, *model); err != nil {
+				return err
+			}
+			update.Set("version", version+1)
+			update.Where("version", OpEqual, version)
+		}
+
+		if len(update.values) == 0 {
+			return nil
+		}
+		statement, args, err := update.Build()
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, statement, args...)
+		if err != nil {
+			return err
+		}
+		if !versioned {
+			return nil
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return s.versionConflict(referenceID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := s.findOne(ctx, s.db, referenceID)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Res: updated, Code: http.StatusOK}, nil
+}
+
+// checkNotReferenced fails if rows of other tables belong to the row with the
+// `id` selfID, their foreign keys can not be cleared
+func (s *SQLResource) checkNotReferenced(ctx context.Context, tx *sql.Tx, referenceID string, selfID interface{}) error {
+	for _, relation := range s.model.GetRelations() {
+		if relation.GetObject() != s.table() || relation.GetRelation() != "belongs_to" {
+			continue
+		}
+		statement, args, err := NewSelectQuery(s.dialect, relation.GetSubject(), "").
+			Where(relation.GetSubject()+"."+relation.GetObjectName(), OpEqual, selfID).
+			CountQuery()
+		if err != nil {
+			return err
+		}
+		var count int
+		if err := tx.QueryRowContext(ctx, statement, args...).Scan(&count); err != nil {
+			return err
+		}
+		if count > 0 {
+			conflict := fmt.Errorf("%s with id %s can not be deleted, %d %s rows belong to it",
+				s.table(), referenceID, count, relation.GetSubject())
+			return NewHTTPError(conflict, conflict.Error(), http.StatusConflict)
+		}
+	}
+	return nil
+}
+
+// Delete removes the row and its join table entries, has_one references to it
+// are set to NULL. Rows which belong to it have to be deleted first, until then
+// Delete fails with 409 Conflict.
+func (s *SQLResource) Delete(id string, req Request) (Responder, error) {
+	ctx := requestContext(req)
+
+	err := s.inTransaction(ctx, func(tx *sql.Tx) error {
+		selfID, err := s.internalID(ctx, tx, s.table(), id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkNotReferenced(ctx, tx, id, selfID); err != nil {
+			return err
+		}
+
+		for _, link := range s.toManyLinks() {
+			if err := s.deleteJoinRows(ctx, tx, link, selfID, nil); err != nil {
+				return err
+			}
+		}
+
+		for _, relation := range s.model.GetRelations() {
+			if relation.GetObject() != s.table() || relation.GetRelation() != "has_one" {
+				continue
+			}
+			statement, args, err := NewUpdateQuery(s.dialect, relation.GetSubject()).
+				Set(relation.GetObjectName(), nil).
+				Where(relation.GetObjectName(), OpEqual, selfID).
+				Build()
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, statement, args...); err != nil {
+				return err
+			}
+		}
+
+		statement, args, err := NewDeleteQuery(s.dialect, s.table()).Where("id", OpEqual, selfID).Build()
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, statement, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Code: http.StatusNoContent}, nil
+}
@@ -0,0 +1,263 @@
+package api2go_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+	_ "modernc.org/sqlite"
+)
+
+// sqlTestAPI serves user, tag and post from an in memory SQLite database.
+// Users have many tags and posts belong to users.
+func sqlTestAPI(t *testing.T) (*api2go.API, *sql.DB, map[string]api2go.Api2GoModel) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every connection would open its own in memory database
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+
+	columns := func(extra ...api2go.ColumnInfo) []api2go.ColumnInfo {
+		return append([]api2go.ColumnInfo{
+			{ColumnName: "id", DataType: "int(11)", IsPrimaryKey: true, IsAutoIncrement: true},
+			{ColumnName: "reference_id", DataType: "varchar(64)", IsUnique: true},
+			{ColumnName: "version", DataType: "int(11)", IsNullable: true},
+		}, extra...)
+	}
+	tags := api2go.NewTableRelation("user", "has_many", "tag")
+	posts := api2go.NewTableRelation("post", "belongs_to", "user")
+
+	models := map[string]api2go.Api2GoModel{
+		"user": api2go.NewApi2GoModel("user", columns(
+			api2go.ColumnInfo{ColumnName: "name", DataType: "varchar(100)", IsNullable: true},
+			api2go.ColumnInfo{ColumnName: "age", DataType: "int(11)", IsNullable: true},
+		), 0, []api2go.TableRelation{tags, posts}),
+		"tag": api2go.NewApi2GoModel("tag", columns(
+			api2go.ColumnInfo{ColumnName: "label", DataType: "varchar(100)", IsNullable: true},
+		), 0, []api2go.TableRelation{tags}),
+		"post": api2go.NewApi2GoModel("post", columns(
+			api2go.ColumnInfo{ColumnName: "title", DataType: "varchar(100)", IsNullable: true},
+		), 0, []api2go.TableRelation{posts}),
+	}
+
+	for _, statement := range api2go.SchemaDDL(api2go.SQLiteDialect, models["user"], models["tag"], models["post"]) {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+
+	api := api2go.NewAPI("v1")
+	for _, name := range []string{"user", "tag", "post"} {
+		api.AddResource(models[name], api2go.NewSQLResource(db, api2go.SQLiteDialect, models[name]))
+	}
+	return api, db, models
+}
+
+func TestSQLResourceCRUD(t *testing.T) {
+	api, _, _ := sqlTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	created := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada", "age": 36}, nil)).expect(http.StatusCreated)
+	if created.attribute("name") != "ada" || created.attribute("age") != "36" {
+		t.Errorf("unexpected attributes %v", created.record().Attributes)
+	}
+	id := created.record().ID
+
+	c.do("GET", "user/"+id, nil).expect(http.StatusOK)
+	c.do("PATCH", "user/"+id, resource("user", id, map[string]interface{}{"name": "grace"}, nil)).expect(http.StatusOK)
+	found := c.do("GET", "user/"+id, nil).expect(http.StatusOK)
+	if found.attribute("name") != "grace" || found.attribute("age") != "36" {
+		t.Errorf("expected only the name to change, got %v", found.record().Attributes)
+	}
+	if listed := c.do("GET", "user", nil).expect(http.StatusOK).records(); len(listed) != 1 {
+		t.Errorf("expected 1 user, got %d", len(listed))
+	}
+
+	c.do("DELETE", "user/"+id, nil).expect(http.StatusNoContent)
+	c.do("GET", "user/"+id, nil).expect(http.StatusNotFound)
+	if listed := c.do("GET", "user", nil).expect(http.StatusOK).records(); len(listed) != 0 {
+		t.Errorf("expected no users, got %d", len(listed))
+	}
+}
+
+func TestSQLResourceVersionAndAudit(t *testing.T) {
+	api, db, models := sqlTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	created := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada"}, nil)).expect(http.StatusCreated)
+	id := created.record().ID
+	if version := created.attribute("version"); version != "1" {
+		t.Errorf("expected version 1, got %s", version)
+	}
+	for i, name := range []string{"grace", "edsger"} {
+		updated := c.do("PATCH", "user/"+id, resource("user", id, map[string]interface{}{"name": name}, nil)).expect(http.StatusOK)
+		if version := updated.attribute("version"); version != fmt.Sprint(i+2) {
+			t.Errorf("expected version %d, got %s", i+2, version)
+		}
+	}
+
+	rows, err := db.Query(`SELECT "name", "version" FROM "user_audit" ORDER BY "version"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var audit []string
+	for rows.Next() {
+		var (
+			name    string
+			version int
+		)
+		if err := rows.Scan(&name, &version); err != nil {
+			t.Fatal(err)
+		}
+		if version != len(audit)+1 {
+			t.Errorf("audit row %d has version %d", len(audit), version)
+		}
+		audit = append(audit, name)
+	}
+	if len(audit) != 2 || audit[0] != "ada" || audit[1] != "grace" {
+		t.Errorf("expected the audit rows ada and grace, got %v", audit)
+	}
+
+	// a client which read version 2 overwrites nothing after version 3
+	stalePatch := resource("user", id, map[string]interface{}{"name": "lost", "version": 2}, nil)
+	c.do("PATCH", "user/"+id, stalePatch).expect(http.StatusConflict)
+	current := c.do("PATCH", "user/"+id, resource("user", id, map[string]interface{}{"name": "barbara", "version": 3}, nil)).expect(http.StatusOK)
+	if current.attribute("name") != "barbara" || current.attribute("version") != "4" {
+		t.Errorf("expected version 4 after an update with the current version, got %v", current.record().Attributes)
+	}
+
+	// a second writer updates the row after it was read
+	source := api2go.NewSQLResource(db, api2go.SQLiteDialect, models["user"])
+	found, err := source.FindOne(id, api2go.Request{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := found.Result().(api2go.Api2GoModel)
+	if _, err := db.Exec(`UPDATE "user" SET "version" = "version" + 1`); err != nil {
+		t.Fatal(err)
+	}
+	attributes := stale.GetAllAsAttributes()
+	attributes["name"] = "stale"
+	stale.SetAttributes(attributes)
+
+	_, err = source.Update(&stale, api2go.Request{})
+	var httpError api2go.HTTPError
+	if !errors.As(err, &httpError) || httpError.Status() != http.StatusConflict {
+		t.Fatalf("expected 409 Conflict for a stale update, got %v", err)
+	}
+	current = c.do("GET", "user/"+id, nil).expect(http.StatusOK)
+	if current.attribute("name") != "barbara" || current.attribute("version") != "5" {
+		t.Errorf("expected the row to be unchanged, got %v", current.record().Attributes)
+	}
+}
+
+func TestSQLResourceListOptions(t *testing.T) {
+	api, _, _ := sqlTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	for _, label := range []string{"b", "d", "a", "c", "e"} {
+		c.do("POST", "tag", resource("tag", "", map[string]interface{}{"label": label}, nil)).expect(http.StatusCreated)
+	}
+
+	// labels returns the labels of the listed tags in order
+	labels := func(r *testResponse) string {
+		var result strings.Builder
+		for _, record := range r.records() {
+			result.WriteString(fmt.Sprint(record.Attributes["label"]))
+		}
+		return result.String()
+	}
+
+	if got := labels(c.do("GET", "tag?sort=-label", nil).expect(http.StatusOK)); got != "edcba" {
+		t.Errorf("expected tags sorted descending, got %s", got)
+	}
+	if got := labels(c.do("GET", "tag?filter[label]=a,c&sort=label", nil).expect(http.StatusOK)); got != "ac" {
+		t.Errorf("expected tags a and c, got %s", got)
+	}
+
+	page := c.do("GET", "tag?sort=label&page[number]=2&page[size]=2", nil).expect(http.StatusOK)
+	if got := labels(page); got != "cd" {
+		t.Errorf("expected the second page to hold c and d, got %s", got)
+	}
+	for _, link := range []string{"prev_page_url", "next_page_url"} {
+		if _, ok := page.Links[link]; !ok {
+			t.Errorf("expected the link %s in %v", link, page.Links)
+		}
+	}
+	if total := fmt.Sprint(page.Links["total"]); total != "5" {
+		t.Errorf("expected a total of 5, got %s", total)
+	}
+
+	c.do("GET", "tag?sort=unknown", nil).expect(http.StatusBadRequest)
+	c.do("GET", "tag?filter[unknown]=x", nil).expect(http.StatusBadRequest)
+}
+
+func TestSQLResourceToMany(t *testing.T) {
+	api, _, _ := sqlTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	tags := make([]string, 3)
+	for i, label := range []string{"a", "b", "c"} {
+		tags[i] = c.do("POST", "tag", resource("tag", "", map[string]interface{}{"label": label}, nil)).expect(http.StatusCreated).record().ID
+	}
+	user := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada"}, map[string]interface{}{
+		"tag_id": identifiers("tag", tags[0]),
+	})).expect(http.StatusCreated).record().ID
+
+	related := func(path string, count int) []testRecord {
+		t.Helper()
+		records := c.do("GET", path, nil).expect(http.StatusOK).records()
+		if len(records) != count {
+			t.Fatalf("expected %d related rows at %s, got %d", count, path, len(records))
+		}
+		return records
+	}
+	related("user/"+user+"/tag_id", 1)
+
+	c.do("POST", "user/"+user+"/relationships/tag_id", identifiers("tag", tags[1], tags[2])).expect(http.StatusNoContent)
+	related("user/"+user+"/tag_id", 3)
+	related("tag/"+tags[2]+"/user_id", 1)
+
+	c.do("DELETE", "user/"+user+"/relationships/tag_id", identifiers("tag", tags[0])).expect(http.StatusNoContent)
+	related("user/"+user+"/tag_id", 2)
+	related("tag/"+tags[0]+"/user_id", 0)
+
+	c.do("PATCH", "user/"+user+"/relationships/tag_id", identifiers("tag", tags[0])).expect(http.StatusNoContent)
+	if records := related("user/"+user+"/tag_id", 1); records[0].ID != tags[0] {
+		t.Errorf("expected only tag %s after replacing, got %s", tags[0], records[0].ID)
+	}
+
+	c.do("PATCH", "user/"+user+"/relationships/tag_id", identifiers("tag")).expect(http.StatusNoContent)
+	related("user/"+user+"/tag_id", 0)
+}
+
+func TestSQLResourceDeleteBelongsTo(t *testing.T) {
+	api, _, _ := sqlTestAPI(t)
+	c := testClient{t, api.Handler()}
+
+	user := c.do("POST", "user", resource("user", "", map[string]interface{}{"name": "ada"}, nil)).expect(http.StatusCreated).record().ID
+	post := c.do("POST", "post", resource("post", "", map[string]interface{}{"title": "notes"}, map[string]interface{}{
+		"user_id": map[string]interface{}{"data": map[string]interface{}{"type": "user", "id": user}},
+	})).expect(http.StatusCreated).record().ID
+	if records := c.do("GET", "user/"+user+"/post_id", nil).expect(http.StatusOK).records(); len(records) != 1 {
+		t.Fatalf("expected 1 post of the user, got %d", len(records))
+	}
+
+	c.do("DELETE", "user/"+user, nil).expect(http.StatusConflict)
+	c.do("GET", "user/"+user, nil).expect(http.StatusOK)
+
+	c.do("DELETE", "post/"+post, nil).expect(http.StatusNoContent)
+	c.do("DELETE", "user/"+user, nil).expect(http.StatusNoContent)
+}
@@ -0,0 +1,233 @@
+package api2go
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Tracer starts spans, adapt it to the tracing library in use
+type Tracer interface {
+	// Start starts a span which is a child of the span in ctx, if any
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// A Span times one phase of a request
+type Span interface {
+	SetAttribute(key string, value interface{})
+	SetError(err error)
+	End()
+}
+
+// The attributes set on the spans of generated routes
+const (
+	SpanAttributeResource  = "api2go.resource"
+	SpanAttributeOperation = "api2go.operation"
+	SpanAttributeID        = "api2go.id"
+	SpanAttributeRelation  = "api2go.relation"
+)
+
+// SetTracer enables tracing. Every generated route gets a span for the
+// request and child spans for the calls to the resource, unmarshalling and
+// marshalling.
+func (api *API) SetTracer(tracer Tracer) {
+	api.tracer = tracer
+}
+
+type traceKey struct{}
+
+// traceContextKey holds the trace state in the APIContexter of a Request,
+// which only supports string keys
+const traceContextKey = "api2go.trace"
+
+// traceState is stored in the request context next to the active span
+type traceState struct {
+	tracer     Tracer
+	span       Span
+	attributes map[string]interface{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(string, interface{}) {}
+func (noopSpan) SetError(error)                   {}
+func (noopSpan) End()                             {}
+
+// traceStateOf returns the trace state of ctx, which is either the context of
+// an http.Request or the Context of a Request
+func traceStateOf(ctx context.Context) (*traceState, bool) {
+	if state, ok := ctx.Value(traceKey{}).(*traceState); ok {
+		return state, true
+	}
+	state, ok := ctx.Value(traceContextKey).(*traceState)
+	return state, ok
+}
+
+// SpanFromContext returns the active span of a request, which can be found in
+// Request.Context and Request.PlainRequest.Context(). It never returns nil.
+func SpanFromContext(ctx context.Context) Span {
+	if state, ok := traceStateOf(ctx); ok {
+		return state.span
+	}
+	return noopSpan{}
+}
+
+// StartSpan starts a child span of the active span with the tracer of the
+// api, so resources can trace their own work
+func StartSpan(ctx context.Context, name string) (context.Context, Span) {
+	state, ok := traceStateOf(ctx)
+	if !ok {
+		return ctx, noopSpan{}
+	}
+	return state.start(ctx, name)
+}
+
+func (state *traceState) start(ctx context.Context, name string) (context.Context, Span) {
+	ctx, span := state.tracer.Start(ctx, name)
+	for key, value := range state.attributes {
+		span.SetAttribute(key, value)
+	}
+	child := &traceState{tracer: state.tracer, span: span, attributes: state.attributes}
+	return context.WithValue(ctx, traceKey{}, child), span
+}
+
+// startRequestSpan starts the span of a generated route
+func (api *API) startRequestSpan(r *http.Request, res *resource, operation Operation, params map[string]string) (*http.Request, Span) {
+	if api.tracer == nil {
+		return r, noopSpan{}
+	}
+	attributes := map[string]interface{}{
+		SpanAttributeResource:  res.name,
+		SpanAttributeOperation: string(operation),
+	}
+	if id, ok := params["id"]; ok {
+		attributes[SpanAttributeID] = id
+	}
+	root := &traceState{tracer: api.tracer, attributes: attributes}
+	ctx, span := root.start(r.Context(), res.name+" "+string(operation))
+	return r.WithContext(ctx), span
+}
+
+// traceRelation adds the relation of a relationship route to the active span
+// and all its children
+func traceRelation(r *http.Request, relation string) *http.Request {
+	state, ok := r.Context().Value(traceKey{}).(*traceState)
+	if !ok {
+		return r
+	}
+	state.span.SetAttribute(SpanAttributeRelation, relation)
+	attributes := make(map[string]interface{}, len(state.attributes)+1)
+	for key, value := range state.attributes {
+		attributes[key] = value
+	}
+	attributes[SpanAttributeRelation] = relation
+	withRelation := &traceState{tracer: state.tracer, span: state.span, attributes: attributes}
+	return r.WithContext(context.WithValue(r.Context(), traceKey{}, withRelation))
+}
+
+// startSpan starts a phase span below the active span and returns r with it
+func startSpan(r *http.Request, name string) (*http.Request, Span) {
+	state, ok := r.Context().Value(traceKey{}).(*traceState)
+	if !ok {
+		return r, noopSpan{}
+	}
+	ctx, span := state.start(r.Context(), name)
+	return r.WithContext(ctx), span
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.SetError(err)
+	}
+	span.End()
+}
+
+// SpanRecorder is a Tracer which keeps all spans in memory, for tests
+type SpanRecorder struct {
+	mutex sync.Mutex
+	spans []*RecordedSpan
+}
+
+// RecordedSpan is a span of a SpanRecorder
+type RecordedSpan struct {
+	Name       string
+	Parent     *RecordedSpan
+	Attributes map[string]interface{}
+	Err        error
+	Start      time.Time
+	End        time.Time
+
+	recorder *SpanRecorder
+}
+
+// NewSpanRecorder returns an empty recorder
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// Start implements Tracer
+func (r *SpanRecorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := SpanFromContext(ctx).(*recordedSpan)
+	span := &RecordedSpan{
+		Name:       name,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+		recorder:   r,
+	}
+	if parent != nil {
+		span.Parent = parent.RecordedSpan
+	}
+
+	r.mutex.Lock()
+	r.spans = append(r.spans, span)
+	r.mutex.Unlock()
+	return ctx, &recordedSpan{span}
+}
+
+// Spans returns copies of all spans in the order they were started
+func (r *SpanRecorder) Spans() []RecordedSpan {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	spans := make([]RecordedSpan, 0, len(r.spans))
+	for _, span := range r.spans {
+		copied := *span
+		copied.Attributes = make(map[string]interface{}, len(span.Attributes))
+		for key, value := range span.Attributes {
+			copied.Attributes[key] = value
+		}
+		spans = append(spans, copied)
+	}
+	return spans
+}
+
+// Reset forgets all spans
+func (r *SpanRecorder) Reset() {
+	r.mutex.Lock()
+	r.spans = nil
+	r.mutex.Unlock()
+}
+
+// recordedSpan implements Span for a RecordedSpan
+type recordedSpan struct {
+	*RecordedSpan
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mutex.Lock()
+	s.Attributes[key] = value
+	s.recorder.mutex.Unlock()
+}
+
+func (s *recordedSpan) SetError(err error) {
+	s.recorder.mutex.Lock()
+	s.Err = err
+	s.recorder.mutex.Unlock()
+}
+
+func (s *recordedSpan) End() {
+	s.recorder.mutex.Lock()
+	s.RecordedSpan.End = time.Now()
+	s.recorder.mutex.Unlock()
+}
@@ -0,0 +1,108 @@
+package api2go_test
+
+import (
+	"net/http"
+	"testing"
+
+	api2go "github.com/artpar/api2go/v2"
+)
+
+// refetchingResource answers updates without a result, so the handler fetches
+// the row again, and starts a span of its own in FindOne
+type refetchingResource struct {
+	*api2go.MemoryResource
+}
+
+func (r refetchingResource) FindOne(ID string, req api2go.Request) (api2go.Responder, error) {
+	_, span := api2go.StartSpan(req.Context, "query")
+	defer span.End()
+	return r.MemoryResource.FindOne(ID, req)
+}
+
+func (r refetchingResource) Update(obj interface{}, req api2go.Request) (api2go.Responder, error) {
+	api2go.SpanFromContext(req.Context).SetAttribute("source", "update")
+	api2go.SpanFromContext(req.PlainRequest.Context()).SetAttribute("plain", true)
+	if _, err := r.MemoryResource.Update(obj, req); err != nil {
+		return nil, err
+	}
+	return &api2go.Response{Code: http.StatusOK}, nil
+}
+
+func TestTracingUpdate(t *testing.T) {
+	models := memoryTestModels()
+	store := api2go.NewMemoryStore()
+	api := api2go.NewAPI("v1")
+	api.AddResource(models["user"], refetchingResource{store.Resource(models["user"])})
+	api.AddResource(models["tag"], store.Resource(models["tag"]))
+	api.AddResource(models["post"], store.Resource(models["post"]))
+	if err := store.Seed([]byte(memoryTestSeed)); err != nil {
+		t.Fatal(err)
+	}
+	recorder := api2go.NewSpanRecorder()
+	api.SetTracer(recorder)
+
+	c := testClient{t, api.Handler()}
+	c.do("PATCH", "user/u1", resource("user", "u1", map[string]interface{}{"name": "grace"}, nil)).expect(http.StatusOK)
+
+	spans := recorder.Spans()
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	want := []string{"user update", "FindOne", "query", "Unmarshal", "Update", "Refetch", "query", "Marshal"}
+	if len(names) != len(want) {
+		t.Fatalf("expected the spans %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("expected the spans %v, got %v", want, names)
+		}
+	}
+
+	root := spans[0]
+	if root.Parent != nil {
+		t.Errorf("expected the request span to have no parent, got %s", root.Parent.Name)
+	}
+	for _, i := range []int{1, 3, 4, 5, 7} {
+		if spans[i].Parent == nil || spans[i].Parent.Name != root.Name {
+			t.Errorf("expected %s to be a child of the request span", spans[i].Name)
+		}
+	}
+	for _, i := range []int{2, 6} {
+		if spans[i].Parent == nil || spans[i].Parent.Name != spans[i-1].Name {
+			t.Errorf("expected the query span %d to be a child of %s", i, spans[i-1].Name)
+		}
+	}
+
+	for i, span := range spans {
+		if span.End.IsZero() {
+			t.Errorf("expected span %s to be ended", span.Name)
+		}
+		if span.Attributes[api2go.SpanAttributeResource] != "user" || span.Attributes[api2go.SpanAttributeOperation] != "update" || span.Attributes[api2go.SpanAttributeID] != "u1" {
+			t.Errorf("expected the request attributes on span %d %s, got %v", i, span.Name, span.Attributes)
+		}
+		if span.Err != nil {
+			t.Errorf("expected no error on span %s, got %v", span.Name, span.Err)
+		}
+	}
+	if update := spans[4]; update.Attributes["source"] != "update" || update.Attributes["plain"] != true {
+		t.Errorf("expected the Update span to be reachable from both contexts, got %v", update.Attributes)
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	api, _, _ := memoryTestAPI(t)
+	recorder := api2go.NewSpanRecorder()
+	api.SetTracer(recorder)
+
+	c := testClient{t, api.Handler()}
+	c.do("GET", "user/unknown", nil).expect(http.StatusNotFound)
+
+	spans := recorder.Spans()
+	if len(spans) < 2 || spans[1].Name != "FindOne" {
+		t.Fatalf("expected a FindOne span, got %v", spans)
+	}
+	if spans[1].Err == nil {
+		t.Error("expected the FindOne span to record the error")
+	}
+}
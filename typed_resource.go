@@ -0,0 +1,221 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/artpar/api2go/v2/jsonapi"
+)
+
+const (
+	typedStatusKey = "api2go.typed.status"
+	typedMetaKey   = "api2go.typed.meta"
+)
+
+// TypedCRUD is the type safe counterpart of CRUD. T is either a struct or a
+// pointer to a struct, api2go takes care of dereferencing.
+type TypedCRUD[T jsonapi.MarshalIdentifier] interface {
+	// FindOne returns an object by its ID, default status 200
+	FindOne(ID string, req Request) (T, error)
+	// Create a new object, default status 201
+	Create(obj T, req Request) (T, error)
+	// Update an object, default status 200
+	Update(obj T, req Request) (T, error)
+	// Delete an object, default status 204
+	Delete(ID string, req Request) error
+}
+
+// TypedFindAll can be optionally implemented by a TypedCRUD source to fetch
+// all records at once
+type TypedFindAll[T jsonapi.MarshalIdentifier] interface {
+	FindAll(req Request) ([]T, error)
+}
+
+// TypedPaginatedFindAll can be optionally implemented by a TypedCRUD source
+// to fetch a subset of all records, see PaginatedFindAll
+type TypedPaginatedFindAll[T jsonapi.MarshalIdentifier] interface {
+	PaginatedFindAll(req Request) (totalCount uint, result []T, err error)
+}
+
+// SetResponseStatus overrides the status code of the response of a typed
+// resource method, e.g. http.StatusAccepted or http.StatusNoContent
+func SetResponseStatus(req Request, status int) {
+	if req.Context != nil {
+		req.Context.Set(typedStatusKey, status)
+	}
+}
+
+// SetResponseMeta adds a meta value to the response of a typed resource method
+func SetResponseMeta(req Request, key string, value interface{}) {
+	if req.Context == nil {
+		return
+	}
+	meta, _ := req.Context.Get(typedMetaKey)
+	values, ok := meta.(map[string]interface{})
+	if !ok {
+		values = make(map[string]interface{})
+		req.Context.Set(typedMetaKey, values)
+	}
+	values[key] = value
+}
+
+// AddTypedResource registers a type safe data source for T. The optional
+// TypedFindAll, TypedPaginatedFindAll and ObjectInitializer interfaces of
+// source are used when implemented.
+func AddTypedResource[T jsonapi.MarshalIdentifier](api *API, source TypedCRUD[T]) {
+	var prototype T
+	prototypeType := reflect.TypeOf((*T)(nil)).Elem()
+	if prototypeType.Kind() == reflect.Ptr {
+		prototype = reflect.New(prototypeType.Elem()).Interface().(T)
+	}
+
+	resource := &typedResource[T]{source: source}
+	if _, ok := source.(TypedPaginatedFindAll[T]); ok {
+		api.AddResource(prototype, &typedPaginatedResource[T]{resource})
+		return
+	}
+	api.AddResource(prototype, resource)
+}
+
+// typedResource bridges a TypedCRUD to the Responder based interfaces
+type typedResource[T jsonapi.MarshalIdentifier] struct {
+	source TypedCRUD[T]
+}
+
+// typedPaginatedResource bridges a TypedCRUD which implements
+// TypedPaginatedFindAll. Other sources must not implement PaginatedFindAll,
+// the API would answer page requests with all records.
+type typedPaginatedResource[T jsonapi.MarshalIdentifier] struct {
+	*typedResource[T]
+}
+
+// Compile time checks
+var (
+	_ CRUD              = &typedResource[Api2GoModel]{}
+	_ FindAll           = &typedResource[Api2GoModel]{}
+	_ ObjectInitializer = &typedResource[Api2GoModel]{}
+	_ PaginatedFindAll  = &typedPaginatedResource[Api2GoModel]{}
+)
+
+// object converts obj as passed by the handlers to T
+func (t *typedResource[T]) object(obj interface{}) (T, error) {
+	if typed, ok := obj.(T); ok {
+		return typed, nil
+	}
+
+	var zero T
+	value := reflect.ValueOf(obj)
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	switch {
+	case value.Kind() == reflect.Ptr && !value.IsNil() && value.Elem().Type() == target:
+		return value.Elem().Interface().(T), nil
+	case target.Kind() == reflect.Ptr && value.IsValid() && value.Type() == target.Elem():
+		pointer := reflect.New(value.Type())
+		pointer.Elem().Set(value)
+		return pointer.Interface().(T), nil
+	}
+	return zero, NewHTTPError(fmt.Errorf("expected %s, got %T", target, obj), http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func (t *typedResource[T]) respond(req Request, result interface{}, status int) Responder {
+	response := &Response{Res: result, Code: status}
+	if req.Context == nil {
+		return response
+	}
+	if override, ok := req.Context.Get(typedStatusKey); ok {
+		if code, ok := override.(int); ok {
+			response.Code = code
+		}
+	}
+	if meta, ok := req.Context.Get(typedMetaKey); ok {
+		response.Meta, _ = meta.(map[string]interface{})
+	}
+	// handleUpdate calls FindOne and Update with the same request
+	req.Context.Set(typedStatusKey, nil)
+	req.Context.Set(typedMetaKey, nil)
+	return response
+}
+
+// InitializeObject forwards to the source if it implements ObjectInitializer
+func (t *typedResource[T]) InitializeObject(obj interface{}) {
+	if initializer, ok := t.source.(ObjectInitializer); ok {
+		initializer.InitializeObject(obj)
+	}
+}
+
+// FindOne calls FindOne of the source
+func (t *typedResource[T]) FindOne(ID string, req Request) (Responder, error) {
+	result, err := t.source.FindOne(ID, req)
+	if err != nil {
+		return nil, err
+	}
+	return t.respond(req, result, http.StatusOK), nil
+}
+
+// FindAll calls FindAll of the source, or PaginatedFindAll if only that is implemented
+func (t *typedResource[T]) FindAll(req Request) (Responder, error) {
+	var result []T
+	var err error
+	switch source := t.source.(type) {
+	case TypedFindAll[T]:
+		result, err = source.FindAll(req)
+	case TypedPaginatedFindAll[T]:
+		_, result, err = source.PaginatedFindAll(req)
+	default:
+		return nil, NewHTTPError(nil, "Resource does not implement the FindAll interface", http.StatusNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = []T{}
+	}
+	return t.respond(req, result, http.StatusOK), nil
+}
+
+// PaginatedFindAll calls PaginatedFindAll of the source
+func (t *typedPaginatedResource[T]) PaginatedFindAll(req Request) (uint, Responder, error) {
+	total, result, err := t.source.(TypedPaginatedFindAll[T]).PaginatedFindAll(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	if result == nil {
+		result = []T{}
+	}
+	return total, t.respond(req, result, http.StatusOK), nil
+}
+
+// Create calls Create of the source with obj converted to T
+func (t *typedResource[T]) Create(obj interface{}, req Request) (Responder, error) {
+	typed, err := t.object(obj)
+	if err != nil {
+		return nil, err
+	}
+	result, err := t.source.Create(typed, req)
+	if err != nil {
+		return nil, err
+	}
+	return t.respond(req, result, http.StatusCreated), nil
+}
+
+// Update calls Update of the source with obj converted to T
+func (t *typedResource[T]) Update(obj interface{}, req Request) (Responder, error) {
+	typed, err := t.object(obj)
+	if err != nil {
+		return nil, err
+	}
+	result, err := t.source.Update(typed, req)
+	if err != nil {
+		return nil, err
+	}
+	return t.respond(req, result, http.StatusOK), nil
+}
+
+// Delete calls Delete of the source
+func (t *typedResource[T]) Delete(ID string, req Request) (Responder, error) {
+	if err := t.source.Delete(ID, req); err != nil {
+		return nil, err
+	}
+	return t.respond(req, nil, http.StatusNoContent), nil
+}
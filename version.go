@@ -0,0 +1,145 @@
+package api2go
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deprecation marks an API version or a resource as deprecated. All its
+// responses get the Deprecation, Sunset and Link headers.
+type Deprecation struct {
+	// Since is when it was deprecated, sent as Deprecation: @<unix time>.
+	// If zero, Deprecation: true is sent.
+	Since time.Time
+	// Sunset is when it will stop working, optional
+	Sunset time.Time
+	// Successor is the URL of the version to use instead, sent as a link with
+	// rel="successor-version"
+	Successor string
+	// Info is the URL of a document describing the deprecation, sent as a
+	// link with rel="deprecation"
+	Info string
+}
+
+// SetVersion names the version of the api for a VersionDispatcher
+func (api *API) SetVersion(version string) {
+	api.version = version
+}
+
+// Version returns the name set with SetVersion
+func (api *API) Version() string {
+	return api.version
+}
+
+// Deprecate marks the whole api version as deprecated
+func (api *API) Deprecate(deprecation Deprecation) {
+	api.deprecation = &deprecation
+}
+
+// DeprecateResource marks a single resource as deprecated, which overrides
+// the deprecation of the api for its routes
+func (api *API) DeprecateResource(resourceName string, deprecation Deprecation) {
+	if api.resourceDeprecations == nil {
+		api.resourceDeprecations = make(map[string]Deprecation)
+	}
+	api.resourceDeprecations[resourceName] = deprecation
+}
+
+// writeDeprecationHeaders adds the headers of the deprecation of the resource
+// or the api, if any. resourceName may be empty.
+func (api *API) writeDeprecationHeaders(w http.ResponseWriter, resourceName string) {
+	deprecation, ok := api.resourceDeprecations[resourceName]
+	if !ok {
+		if api.deprecation == nil {
+			return
+		}
+		deprecation = *api.deprecation
+	}
+
+	header := w.Header()
+	if deprecation.Since.IsZero() {
+		header.Set("Deprecation", "true")
+	} else {
+		header.Set("Deprecation", "@"+strconv.FormatInt(deprecation.Since.Unix(), 10))
+	}
+	if !deprecation.Sunset.IsZero() {
+		header.Set("Sunset", deprecation.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if deprecation.Successor != "" {
+		header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, deprecation.Successor))
+	}
+	if deprecation.Info != "" {
+		header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, deprecation.Info))
+	}
+}
+
+// VersionDispatcher serves several API instances under one prefix and picks
+// one by a request header like Accept-Version
+type VersionDispatcher struct {
+	header         string
+	defaultVersion string
+	handlers       map[string]http.Handler
+}
+
+// NewVersionDispatcher dispatches by the given header to the apis, which need
+// a version set with SetVersion. Requests without the header go to the first
+// api.
+func NewVersionDispatcher(header string, apis ...*API) *VersionDispatcher {
+	if len(apis) == 0 {
+		panic("a version dispatcher needs at least one api")
+	}
+	d := &VersionDispatcher{
+		header:         header,
+		defaultVersion: apis[0].version,
+		handlers:       make(map[string]http.Handler, len(apis)),
+	}
+	for _, api := range apis {
+		if api.version == "" {
+			panic("every api of a version dispatcher needs a version")
+		}
+		if _, ok := d.handlers[api.version]; ok {
+			panic("api version " + api.version + " is registered twice")
+		}
+		d.handlers[api.version] = api.router.Handler()
+	}
+	return d
+}
+
+// Versions returns the supported versions in alphabetical order
+func (d *VersionDispatcher) Versions() []string {
+	versions := make([]string, 0, len(d.handlers))
+	for version := range d.handlers {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// ServeHTTP implements http.Handler, unknown versions are answered with
+// 406 Not Acceptable
+func (d *VersionDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Vary", d.header)
+
+	version := strings.TrimSpace(r.Header.Get(d.header))
+	if version == "" {
+		version = d.defaultVersion
+	}
+	handler, ok := d.handlers[version]
+	if !ok {
+		title := fmt.Sprintf("API version %s is not supported, supported versions are %s",
+			version, strings.Join(d.Versions(), ", "))
+		httpError := NewHTTPError(nil, title, http.StatusNotAcceptable)
+		httpError.Errors = append(httpError.Errors, Error{
+			Status: strconv.Itoa(http.StatusNotAcceptable),
+			Code:   codeUnsupportedVersion,
+			Title:  title,
+		})
+		handleError(httpError, w, r, defaultContentTypHeader)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}